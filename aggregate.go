@@ -0,0 +1,90 @@
+package eywa
+
+import "fmt"
+
+// aggregateField is an aggregate function over the rows of an array
+// relationship, for use with OrderByAggregate and AggregateWhere.
+type aggregateField struct {
+	function string
+	field    string
+}
+
+// Count returns the row count aggregate.
+func Count() aggregateField {
+	return aggregateField{function: "count"}
+}
+
+// Sum returns the sum aggregate over field.
+func Sum[M Model, FN FieldName[M]](field FN) aggregateField {
+	return aggregateField{"sum", string(field)}
+}
+
+// Avg returns the average aggregate over field.
+func Avg[M Model, FN FieldName[M]](field FN) aggregateField {
+	return aggregateField{"avg", string(field)}
+}
+
+// Min returns the minimum aggregate over field.
+func Min[M Model, FN FieldName[M]](field FN) aggregateField {
+	return aggregateField{"min", string(field)}
+}
+
+// Max returns the maximum aggregate over field.
+func Max[M Model, FN FieldName[M]](field FN) aggregateField {
+	return aggregateField{"max", string(field)}
+}
+
+// aggregateCond compares an aggregate with a value, for use with
+// AggregateWhere.
+type aggregateCond struct {
+	field aggregateField
+	oprtr operator
+	value int
+}
+
+func (af aggregateField) compare(oprtr operator, n int) aggregateCond {
+	return aggregateCond{af, oprtr, n}
+}
+
+func (af aggregateField) Eq(n int) aggregateCond {
+	return af.compare(eq, n)
+}
+
+func (af aggregateField) Neq(n int) aggregateCond {
+	return af.compare(neq, n)
+}
+
+func (af aggregateField) Gt(n int) aggregateCond {
+	return af.compare(gt, n)
+}
+
+func (af aggregateField) Gte(n int) aggregateCond {
+	return af.compare(gte, n)
+}
+
+func (af aggregateField) Lt(n int) aggregateCond {
+	return af.compare(lt, n)
+}
+
+func (af aggregateField) Lte(n int) aggregateCond {
+	return af.compare(lte, n)
+}
+
+// AggregateWhere filters rows of M by an aggregate over the rows of the array
+// relationship relField, e.g. AggregateWhere[User]("posts", Count().Gt(5))
+// renders {posts_aggregate: {count: {predicate: {_gt: 5}}}}. Hasura only
+// supports count in where expressions, so other aggregates fail the query
+// with an error when it is validated or run.
+func AggregateWhere[M Model, FN FieldName[M]](relField FN, cond aggregateCond) *WhereExpr {
+	var err error
+	if cond.field.function != "count" {
+		err = fmt.Errorf("eywa: %s aggregate of %s can't be used in a where expression, only count", cond.field.function, relField)
+	}
+	return &WhereExpr{
+		cmp: &comparison{
+			field: fmt.Sprintf("%s_aggregate", relField),
+			expr:  fmt.Sprintf("{count: {predicate: {%s: %d}}}", cond.oprtr, cond.value),
+			err:   err,
+		},
+	}
+}
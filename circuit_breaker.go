@@ -0,0 +1,97 @@
+package eywa
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without sending the request while the circuit
+// breaker installed by WithCircuitBreaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// WithCircuitBreaker returns a ClientMiddleware that stops sending requests
+// after threshold consecutive failures and fails fast with ErrCircuitOpen.
+// Once resetAfter has passed, a single request is let through to probe the
+// backend: the circuit closes if it succeeds and stays open otherwise.
+// Requests canceled by their context don't count, and requests started
+// before the circuit last opened or closed can't change its state. It panics
+// if threshold is not positive.
+func WithCircuitBreaker(threshold int, resetAfter time.Duration) ClientMiddleware {
+	if threshold <= 0 {
+		panic("eywa: circuit breaker threshold must be positive")
+	}
+	cb := &circuitBreaker{
+		threshold:  threshold,
+		resetAfter: resetAfter,
+	}
+	return func(next RequestFunc) RequestFunc {
+		return func(ctx context.Context, q Queryable) (*bytes.Buffer, error) {
+			generation, probe, ok := cb.allow()
+			if !ok {
+				return nil, ErrCircuitOpen
+			}
+			resp, err := next(ctx, q)
+			cb.record(generation, probe, err, ctx.Err() != nil)
+			return resp, err
+		}
+	}
+}
+
+type circuitBreaker struct {
+	mu         sync.Mutex
+	threshold  int
+	resetAfter time.Duration
+	failures   int
+	openedAt   time.Time
+	probing    bool
+	// generation is incremented whenever the circuit opens or closes.
+	generation int
+}
+
+// allow reports whether a request can be sent, the generation it is sent in
+// and whether it is the probe of an open circuit.
+func (cb *circuitBreaker) allow() (generation int, probe, ok bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.failures < cb.threshold {
+		return cb.generation, false, true
+	}
+	if cb.probing || time.Since(cb.openedAt) < cb.resetAfter {
+		return 0, false, false
+	}
+	cb.probing = true
+	return cb.generation, true, true
+}
+
+// record updates the circuit with the result of a request allowed by allow.
+// Results of requests from an earlier generation and of canceled requests
+// are ignored; a canceled probe lets the next request probe again.
+func (cb *circuitBreaker) record(generation int, probe bool, err error, canceled bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if probe {
+		cb.probing = false
+	}
+	if canceled || generation != cb.generation {
+		return
+	}
+	if err == nil {
+		if cb.failures >= cb.threshold {
+			cb.generation++
+		}
+		cb.failures = 0
+		return
+	}
+	if probe {
+		cb.openedAt = time.Now()
+		return
+	}
+	cb.failures++
+	if cb.failures == cb.threshold {
+		cb.openedAt = time.Now()
+		cb.generation++
+	}
+}
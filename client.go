@@ -2,29 +2,50 @@ package eywa
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
+	"time"
 )
 
 type Client struct {
 	endpoint   string
 	httpClient *http.Client
-	headers    map[string]string
+	headers    *headers
+	send       RequestFunc
+
+	autoRequestID bool
 }
 
 type ClientOpts struct {
 	HttpClient *http.Client
 	Headers    map[string]string
+	// Middlewares wrap every request sent by the client. The first middleware
+	// is the outermost one.
+	Middlewares []ClientMiddleware
+	// AutoRequestID generates a request ID for requests whose context has
+	// none set with WithRequestID.
+	AutoRequestID bool
 }
 
+// RequestFunc sends a query and returns the raw response body.
+type RequestFunc func(ctx context.Context, q Queryable) (*bytes.Buffer, error)
+
+// ClientMiddleware wraps the RequestFunc used by a Client, e.g. to add
+// retries, logging or metrics around every request.
+type ClientMiddleware func(next RequestFunc) RequestFunc
+
 // NewClient accepts a graphql endpoint and returns back a Client.
 // It uses the http.DefaultClient as the underlying http client by default.
 func NewClient(gqlEndpoint string, opt *ClientOpts) *Client {
 	c := &Client{
 		endpoint:   gqlEndpoint,
 		httpClient: http.DefaultClient,
+		headers:    &headers{values: map[string]string{}},
 	}
 
 	if opt != nil {
@@ -32,49 +53,200 @@ func NewClient(gqlEndpoint string, opt *ClientOpts) *Client {
 			c.httpClient = opt.HttpClient
 		}
 
-		if opt.Headers != nil && len(opt.Headers) > 0 {
-			c.headers = opt.Headers
+		for key, value := range opt.Headers {
+			c.headers.values[key] = value
+		}
+
+		c.autoRequestID = opt.AutoRequestID
+	}
+
+	c.send = c.post
+	if opt != nil {
+		for i := len(opt.Middlewares) - 1; i >= 0; i-- {
+			c.send = opt.Middlewares[i](c.send)
 		}
 	}
 
 	return c
 }
 
-func (c *Client) do(q Queryable) (*bytes.Buffer, error) {
+// NewClientWithAdminSecret returns a Client that authenticates with the given
+// Hasura admin secret.
+func NewClientWithAdminSecret(gqlEndpoint, secret string) *Client {
+	return NewClient(gqlEndpoint, &ClientOpts{
+		Headers: map[string]string{
+			adminSecretHeader: secret,
+		},
+	})
+}
+
+// NewClientWithTLS returns a Client whose http client uses tlsCfg, e.g. with
+// a client certificate for mutual TLS. The transport is a copy of
+// http.DefaultTransport that keeps more idle connections to the endpoint
+// open. The HttpClient in opts is ignored.
+func NewClientWithTLS(gqlEndpoint string, tlsCfg *tls.Config, opts *ClientOpts) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 90 * time.Second
+
+	var o ClientOpts
+	if opts != nil {
+		o = *opts
+	}
+	o.HttpClient = &http.Client{Transport: transport}
+	return NewClient(gqlEndpoint, &o)
+}
+
+// NewClientWithJWT returns a Client that sends token as a bearer token in the
+// Authorization header.
+func NewClientWithJWT(gqlEndpoint, token string) *Client {
+	return NewClient(gqlEndpoint, &ClientOpts{
+		Headers: map[string]string{
+			"Authorization": "Bearer " + token,
+		},
+	})
+}
+
+// With returns a copy of the client whose requests are additionally wrapped
+// by mws. The first middleware is the outermost one, and all of them run
+// outside the client's own middlewares.
+func (c *Client) With(mws ...ClientMiddleware) *Client {
+	clone := *c
+	for i := len(mws) - 1; i >= 0; i-- {
+		clone.send = mws[i](clone.send)
+	}
+	return &clone
+}
+
+// headers are the headers sent with every request of a client. They are
+// shared by the copies of the client returned by With.
+type headers struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+func (h *headers) apply(header http.Header) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for key, value := range h.values {
+		header.Add(key, value)
+	}
+}
+
+// SetHeader sets a header sent with every request, replacing any value set
+// before, e.g. to rotate credentials. It is safe to call while requests are
+// in flight; it also affects the clients returned by With.
+func (c *Client) SetHeader(key, value string) {
+	c.headers.mu.Lock()
+	defer c.headers.mu.Unlock()
+	c.headers.values[key] = value
+}
+
+// RemoveHeader stops sending a header set with SetHeader or in ClientOpts.
+func (c *Client) RemoveHeader(key string) {
+	c.headers.mu.Lock()
+	defer c.headers.mu.Unlock()
+	delete(c.headers.values, key)
+}
+
+func (c *Client) do(ctx context.Context, q Queryable) (*bytes.Buffer, error) {
+	if b, ok := q.(builder); ok {
+		if err := b.buildErr(); err != nil {
+			return nil, err
+		}
+	}
+	return c.send(c.withRequestID(ctx), q)
+}
+
+// Do sends an arbitrary query and returns the data field of the response.
+// Errors in the graphql response are joined into the returned error.
+func (c *Client) Do(ctx context.Context, q Queryable) (json.RawMessage, error) {
+	respBytes, err := c.do(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	respObj := graphqlResponse{}
+	err = json.NewDecoder(respBytes).Decode(&respObj)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := graphqlErrorsToError(respObj.Errors); err != nil {
+		return nil, err
+	}
+	return respObj.Data, nil
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []graphqlError  `json:"errors"`
+}
+
+type statusError struct {
+	code int
+}
+
+func (e statusError) Error() string {
+	if e.code < 400 {
+		return fmt.Sprintf("redirected request with http status code: %d", e.code)
+	}
+	return fmt.Sprintf("error response with http status code: %d", e.code)
+}
+
+func (c *Client) post(ctx context.Context, q Queryable) (*bytes.Buffer, error) {
 	reqObj := graphqlRequest{
 		Query:     q.Query(),
 		Variables: q.Variables(),
 	}
+	return c.postJSON(ctx, c.endpoint, &reqObj)
+}
 
+// postJSON sends body as json to url with the client's headers and returns the
+// response body.
+func (c *Client) postJSON(ctx context.Context, url string, body interface{}) (*bytes.Buffer, error) {
 	var reqBytes bytes.Buffer
-	err := json.NewEncoder(&reqBytes).Encode(&reqObj)
+	err := json.NewEncoder(&reqBytes).Encode(body)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest(http.MethodPost, c.endpoint, &reqBytes)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &reqBytes)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Add("Content-Type", "application/json")
-	for key, value := range c.headers {
-		req.Header.Add(key, value)
+	c.headers.apply(req.Header)
+	for key, value := range SessionFromContext(ctx) {
+		req.Header.Set(key, value)
+	}
+	if adminBypassFromContext(ctx) {
+		if err := applyAdminBypass(req.Header); err != nil {
+			return nil, err
+		}
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(requestIDHeader, id)
 	}
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	switch {
-	case resp.StatusCode > 299 && resp.StatusCode < 399:
-		return nil, fmt.Errorf("redirected request with http status code: %d", resp.StatusCode)
-	case resp.StatusCode > 399:
-		return nil, fmt.Errorf("error response with http status code: %d", resp.StatusCode)
-	}
-
 	var respBytes bytes.Buffer
 	_, err = io.Copy(&respBytes, resp.Body)
+	if meta := httpMetaFromContext(ctx); meta != nil {
+		meta.status = resp.StatusCode
+		meta.headers = resp.Header
+		meta.duration = time.Since(start)
+	}
+
+	if resp.StatusCode > 299 {
+		return nil, statusError{resp.StatusCode}
+	}
 	return &respBytes, err
 }
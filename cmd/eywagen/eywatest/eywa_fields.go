@@ -2,12 +2,19 @@
 package eywatest
 
 import (
-	"github.com/imperfect-fourth/eywa"
-	"bytes"
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/imperfect-fourth/eywa"
 )
 
 
+var _ eywa.Model = (*testTable)(nil)
+
+
+func testTableZero() *testTable {
+	return &testTable{}
+}
+
 const testTable_Name eywa.ModelFieldName[testTable] = "name"
 
 func testTable_NameField(val string) eywa.ModelField[testTable] {
@@ -32,12 +39,27 @@ func testTable_AgeField(val *int) eywa.ModelField[testTable] {
 	}
 }
 
+func testTable_AgeInc(delta int) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "age",
+		Value: delta,
+	}
+}
+
+func testTable_AgeDec(delta int) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "age",
+		Value: -delta,
+	}
+}
+
 func testTable_AgeVar(val *int) eywa.ModelField[testTable] {
 	return eywa.ModelField[testTable]{
 		Name: "age",
 		Value: eywa.QueryVar("testTable_Age", eywa.NullableIntVar[*int](val)),
 	}
 }
+const testTable_IDConstraint eywa.Constraint[testTable] = "test_table_pkey"
 const testTable_ID eywa.ModelFieldName[testTable] = "id"
 
 func testTable_IDField(val int) eywa.ModelField[testTable] {
@@ -47,6 +69,20 @@ func testTable_IDField(val int) eywa.ModelField[testTable] {
 	}
 }
 
+func testTable_IDInc(delta int) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "id",
+		Value: delta,
+	}
+}
+
+func testTable_IDDec(delta int) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "id",
+		Value: -delta,
+	}
+}
+
 func testTable_IDVar(val int) eywa.ModelField[testTable] {
 	return eywa.ModelField[testTable]{
 		Name: "id",
@@ -62,6 +98,20 @@ func testTable_iDField(val int32) eywa.ModelField[testTable] {
 	}
 }
 
+func testTable_iDInc(delta int32) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "idd",
+		Value: delta,
+	}
+}
+
+func testTable_iDDec(delta int32) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "idd",
+		Value: -delta,
+	}
+}
+
 func testTable_iDVar(val int32) eywa.ModelField[testTable] {
 	return eywa.ModelField[testTable]{
 		Name: "idd",
@@ -84,15 +134,12 @@ func testTable_customVar[T interface{eywa.JSONValue | eywa.JSONBValue;eywa.Typed
 	}
 }
 
-func testTable_testTable2(subField eywa.ModelFieldName[testTable2], subFields ...eywa.ModelFieldName[testTable2]) string {
-	buf := bytes.NewBuffer([]byte("testTable2 {"))
-	buf.WriteString(string(subField))
-	for _, f := range subFields {
-		buf.WriteString("\n")
-		buf.WriteString(string(f))
-	}
-	buf.WriteString("}")
-	return buf.String()
+func testTable_testTable2(subField eywa.ModelFieldName[testTable2], subFields ...eywa.ModelFieldName[testTable2]) eywa.ModelFieldName[testTable] {
+	return eywa.GetWithRelationship[testTable]("testTable2", subField, subFields...)
+}
+
+func testTable_testTable2Where(where *eywa.WhereExpr, subField eywa.ModelFieldName[testTable2], subFields ...eywa.ModelFieldName[testTable2]) eywa.ModelFieldName[testTable] {
+	return eywa.GetWithRelationshipWhere[testTable]("testTable2", where, subField, subFields...)
 }
 const testTable_JsonBCol eywa.ModelFieldName[testTable] = "jsonb_col"
 
@@ -124,6 +171,178 @@ func testTable_RRVar(val R) eywa.ModelField[testTable] {
 		Value: eywa.QueryVar("testTable_RR", eywa.StringVar[R](val)),
 	}
 }
+const testTable_CreatedAt eywa.ModelFieldName[testTable] = "created_at"
+const testTable_Score eywa.ModelFieldName[testTable] = "score"
+
+func testTable_ScoreField(val float64) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "score",
+		Value: val,
+	}
+}
+
+func testTable_ScoreInc(delta float64) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "score",
+		Value: delta,
+	}
+}
+
+func testTable_ScoreDec(delta float64) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "score",
+		Value: -delta,
+	}
+}
+
+func testTable_ScoreVar(val float64) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "score",
+		Value: eywa.QueryVar("testTable_Score", eywa.FloatVar[float64](val)),
+	}
+}
+const testTable_State eywa.ModelFieldName[testTable] = "state"
+
+func testTable_StateField(val state) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "state",
+		Value: val,
+	}
+}
+
+func testTable_StateVar(val state) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "state",
+		Value: eywa.QueryVar("testTable_State", eywa.StringVar[state](val)),
+	}
+}
+
+func testTable_StateEnumVar(val state) eywa.ModelField[testTable] {
+	return eywa.ModelField[testTable]{
+		Name: "state",
+		Value: eywa.QueryVar("testTable_State", eywa.EnumValue(val)),
+	}
+}
+
+func testTableWith(fields ...eywa.ModelField[testTable]) *testTable {
+	m := &testTable{}
+	for _, f := range fields {
+		var err error
+		switch f.Name {
+		case "name":
+			m.Name, err = eywa.FieldValue[string](f)
+		case "age":
+			m.Age, err = eywa.FieldValue[*int](f)
+		case "id":
+			m.ID, err = eywa.FieldValue[int](f)
+		case "idd":
+			m.iD, err = eywa.FieldValue[int32](f)
+		case "custom":
+			m.custom, err = eywa.FieldValue[*customType](f)
+		case "jsonb_col":
+			m.JsonBCol, err = eywa.FieldValue[jsonbcol](f)
+		case "r":
+			m.RR, err = eywa.FieldValue[R](f)
+		case "created_at":
+			m.CreatedAt, err = eywa.FieldValue[*string](f)
+		case "score":
+			m.Score, err = eywa.FieldValue[float64](f)
+		case "state":
+			m.State, err = eywa.FieldValue[state](f)
+		default:
+			err = fmt.Errorf("testTable has no field %q", f.Name)
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
+	return m
+}
+
+var testTableFields = []eywa.ModelFieldName[testTable]{
+	testTable_Name,
+	testTable_Age,
+	testTable_ID,
+	testTable_iD,
+	testTable_custom,
+	testTable_JsonBCol,
+	testTable_RR,
+	testTable_CreatedAt,
+	testTable_Score,
+	testTable_State,
+}
+
+var testTablePrimaryKey = []eywa.ModelFieldName[testTable]{
+	testTable_ID,
+}
+
+type testTableBuilder struct {
+	m   testTable
+	set map[string]bool
+}
+
+func NewtestTableBuilder() *testTableBuilder {
+	return &testTableBuilder{set: map[string]bool{}}
+}
+
+func (b *testTableBuilder) Name(val string) *testTableBuilder {
+	b.m.Name = val
+	b.set["name"] = true
+	return b
+}
+
+func (b *testTableBuilder) Age(val *int) *testTableBuilder {
+	b.m.Age = val
+	b.set["age"] = true
+	return b
+}
+
+func (b *testTableBuilder) ID(val int) *testTableBuilder {
+	b.m.ID = val
+	b.set["id"] = true
+	return b
+}
+
+func (b *testTableBuilder) JsonBCol(val jsonbcol) *testTableBuilder {
+	b.m.JsonBCol = val
+	b.set["jsonb_col"] = true
+	return b
+}
+
+func (b *testTableBuilder) RR(val R) *testTableBuilder {
+	b.m.RR = val
+	b.set["r"] = true
+	return b
+}
+
+func (b *testTableBuilder) Score(val float64) *testTableBuilder {
+	b.m.Score = val
+	b.set["score"] = true
+	return b
+}
+
+func (b *testTableBuilder) State(val state) *testTableBuilder {
+	b.m.State = val
+	b.set["state"] = true
+	return b
+}
+
+func (b *testTableBuilder) Build() (*testTable, error) {
+	for _, f := range []string{"name", "age", "jsonb_col", "r", "score", "state"} {
+		if !b.set[f] {
+			return nil, fmt.Errorf("testTable: required field %s not set", f)
+		}
+	}
+	m := b.m
+	return &m, nil
+}
+
+var _ eywa.Model = (*testTable2)(nil)
+
+
+func testTable2Zero() *testTable2 {
+	return &testTable2{}
+}
 
 const testTable2_ID eywa.ModelFieldName[testTable2] = "id"
 
@@ -133,3 +352,49 @@ func testTable2_IDField(val uuid.UUID) eywa.ModelField[testTable2] {
 		Value: val,
 	}
 }
+
+func testTable2With(fields ...eywa.ModelField[testTable2]) *testTable2 {
+	m := &testTable2{}
+	for _, f := range fields {
+		var err error
+		switch f.Name {
+		case "id":
+			m.ID, err = eywa.FieldValue[uuid.UUID](f)
+		default:
+			err = fmt.Errorf("testTable2 has no field %q", f.Name)
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
+	return m
+}
+
+var testTable2Fields = []eywa.ModelFieldName[testTable2]{
+	testTable2_ID,
+}
+
+type testTable2Builder struct {
+	m   testTable2
+	set map[string]bool
+}
+
+func NewtestTable2Builder() *testTable2Builder {
+	return &testTable2Builder{set: map[string]bool{}}
+}
+
+func (b *testTable2Builder) ID(val uuid.UUID) *testTable2Builder {
+	b.m.ID = val
+	b.set["id"] = true
+	return b
+}
+
+func (b *testTable2Builder) Build() (*testTable2, error) {
+	for _, f := range []string{"id"} {
+		if !b.set[f] {
+			return nil, fmt.Errorf("testTable2: required field %s not set", f)
+		}
+	}
+	m := b.m
+	return &m, nil
+}
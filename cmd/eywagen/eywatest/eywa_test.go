@@ -1,9 +1,12 @@
 package eywatest
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/imperfect-fourth/eywa"
 	"github.com/stretchr/testify/assert"
 )
@@ -32,7 +35,7 @@ name
 			},
 		})
 
-		resp, err := q.Exec(c)
+		resp, err := q.Exec(context.Background(), c)
 
 		assert.NoError(t, err)
 		assert.Equal(t, []testTable{{Name: "abcd"}, {Name: "abc"}}, resp)
@@ -79,10 +82,186 @@ name
 			},
 		})
 
-		resp, err := q.Exec(c)
+		resp, err := q.Exec(context.Background(), c)
 
 		assert.NoError(t, err)
 		n := 3
 		assert.Equal(t, []testTable{{ID: n, Name: "updatetest"}}, resp)
 	}
 }
+
+func TestRelationshipWhere(t *testing.T) {
+	q := eywa.Get[testTable]().Select(
+		testTable_Name,
+		testTable_testTable2Where(
+			eywa.Eq[testTable2](testTable2_IDField(uuid.Nil)),
+			testTable2_ID,
+		),
+	)
+	expected := `query get_test_table {
+test_table {
+testTable2(where: {id: {_eq: "00000000-0000-0000-0000-000000000000"}}) {id}
+name
+}
+}`
+	assert.Equal(t, expected, q.Query())
+
+	assert.PanicsWithValue(t, "eywa: where of relationship testTable2 uses query variable $id; use a value instead", func() {
+		testTable_testTable2Where(
+			eywa.Eq[testTable2](eywa.ModelField[testTable2]{Name: "id", Value: eywa.QueryVar("id", eywa.StringVar("x"))}),
+			testTable2_ID,
+		)
+	})
+}
+
+func TestGetWithRelationship(t *testing.T) {
+	rel := eywa.GetWithRelationship[testTable]("testTable2", testTable2_ID)
+	assert.Equal(t, testTable_testTable2(testTable2_ID), rel)
+}
+
+func TestSelectAllFields(t *testing.T) {
+	q := eywa.Get[testTable2]().SelectFields(testTable2Fields)
+	assert.Equal(t, "query get_test_table2 {\ntest_table2 {\nid\n}\n}", q.Query())
+	assert.NoError(t, q.Validate())
+
+	u := eywa.Update[testTable]().Where(
+		eywa.Eq[testTable](testTable_IDField(1)),
+	).Set(testTable_NameField("a")).SelectFields(testTableFields)
+	assert.NoError(t, u.Validate())
+
+	q = eywa.Get[testTable2]().SelectFields(nil)
+	assert.ErrorIs(t, q.Validate(), eywa.ErrNoFields)
+	_, err := q.Exec(context.Background(), eywa.NewClient("http://localhost:0", nil))
+	assert.ErrorIs(t, err, eywa.ErrNoFields)
+}
+
+func TestUpdateIncDec(t *testing.T) {
+	q := eywa.Update[testTable]().Where(
+		eywa.Eq[testTable](testTable_IDField(1)),
+	).Inc(
+		testTable_AgeInc(2),
+		testTable_iDDec(3),
+		testTable_ScoreDec(0.5),
+	).Select(testTable_Age)
+
+	expected := `mutation update_test_table {
+update_test_table(where: {id: {_eq: 1}}, _inc: {age: 2, idd: -3, score: -0.5}) {
+returning {
+age
+}
+}
+}`
+	assert.Equal(t, expected, q.Query())
+}
+
+func TestUpdateManyVars(t *testing.T) {
+	q := eywa.UpdateMany(
+		eywa.Update[testTable]().Where(
+			eywa.Eq[testTable](testTable_IDVar(1)),
+		).Set(testTable_NameVar("first")),
+		eywa.Update[testTable]().Where(
+			eywa.Eq[testTable](testTable_IDVar(2)),
+		).Set(testTable_NameVar("second")),
+	).Select(testTable_ID)
+
+	expected := `mutation update_test_table_many($testTable_Name_0: String!, $testTable_ID_0: Int!, $testTable_Name_1: String!, $testTable_ID_1: Int!) {
+update_test_table_many(updates: [{where: {id: {_eq: $testTable_ID_0}}, _set: {name: $testTable_Name_0}}, {where: {id: {_eq: $testTable_ID_1}}, _set: {name: $testTable_Name_1}}]) {
+affected_rows
+returning {
+id
+}
+}
+}`
+	assert.Equal(t, expected, q.Query())
+	assert.Equal(t, map[string]interface{}{
+		"testTable_ID_0":   1,
+		"testTable_Name_0": "first",
+		"testTable_ID_1":   2,
+		"testTable_Name_1": "second",
+	}, q.Variables())
+	assert.NoError(t, q.Validate())
+
+	q = eywa.UpdateMany(
+		eywa.Update[testTable]().Where(
+			eywa.Eq[testTable](testTable_IDVar(1)),
+		).Set(testTable_IDVar(2)),
+	).Select(testTable_ID)
+	assert.EqualError(t, q.Validate(), "update 0: variable $testTable_ID is used with different values")
+}
+
+func TestModelConstructors(t *testing.T) {
+	assert.Equal(t, &testTable{}, testTableZero())
+
+	age := 3
+	m := testTableWith(testTable_NameField("abc"), testTable_AgeField(&age), testTable_ScoreField(1.5))
+	assert.Equal(t, &testTable{Name: "abc", Age: &age, Score: 1.5}, m)
+
+	c := &customType{}
+	m = testTableWith(testTable_iDField(1), testTable_customField(c), testTable_IDVar(2), testTable_StateEnumVar("active"), testTable_JsonBColVar[eywa.JSONBValue](jsonbcol{}))
+	assert.Equal(t, &testTable{iD: 1, custom: c, ID: 2, State: "active"}, m)
+
+	assert.Panics(t, func() { testTableWith(eywa.ModelField[testTable]{Name: "unknown"}) })
+}
+
+func TestApplyFieldsVar(t *testing.T) {
+	m := &testTable{}
+	assert.NoError(t, eywa.ApplyFields(m, testTable_NameVar("abc"), testTable_ScoreVar(1.5)))
+	assert.Equal(t, &testTable{Name: "abc", Score: 1.5}, m)
+	assert.EqualError(t, eywa.ApplyFields(m, testTable_iDField(1)), `eywa: eywatest.testTable has no exported field "idd"`)
+}
+
+func TestEnumVar(t *testing.T) {
+	q := eywa.Update[testTable]().Where(
+		eywa.Eq[testTable](testTable_IDField(1)),
+	).Set(
+		testTable_StateEnumVar("active"),
+	).Select(testTable_State)
+
+	expected := `mutation update_test_table($testTable_State: test_table_state_enum!) {
+update_test_table(where: {id: {_eq: 1}}, _set: {state: $testTable_State}) {
+returning {
+state
+}
+}
+}`
+	assert.Equal(t, expected, q.Query())
+	assert.Equal(t, map[string]interface{}{"testTable_State": "active"}, q.Variables())
+}
+
+func TestModelFieldNameString(t *testing.T) {
+	assert.Equal(t, "name", testTable_Name.String())
+	assert.Equal(t, "name", fmt.Sprint(testTable_Name))
+}
+
+func TestModelFieldNameOrderBy(t *testing.T) {
+	q := eywa.Get[testTable]().OrderBy(testTable_ID.Desc(), testTable_Name.Asc()).Select(testTable_Name)
+	assert.Equal(t, "query get_test_table {\ntest_table(order_by: {id: desc, name: asc}) {\nname\n}\n}", q.Query())
+}
+
+func TestAlias(t *testing.T) {
+	q := eywa.Get[testTable]().StrictSelect().Select(testTable_ID, eywa.Alias("r", testTable_Name))
+	assert.Equal(t, "query get_test_table {\ntest_table {\nr: name\nid\n}\n}", q.Query())
+	assert.NoError(t, q.Validate())
+
+	q = eywa.Get[testTable]().StrictSelect().Select(eywa.Alias("full_name", testTable_Name))
+	assert.EqualError(t, q.Validate(), `unknown field "full_name" selected on test_table`)
+}
+
+func TestModelFieldString(t *testing.T) {
+	assert.Equal(t, `name: "abcd"`, testTable_NameField("abcd").String())
+	assert.Equal(t, "score: 1.5", fmt.Sprint(testTable_ScoreField(1.5)))
+}
+
+func TestModelBuilder(t *testing.T) {
+	age := 30
+	m, err := NewtestTableBuilder().Name("abcd").Age(&age).JsonBCol(jsonbcol{}).RR("r").Score(1).State("active").Build()
+	assert.NoError(t, err)
+	assert.Equal(t, &testTable{Name: "abcd", Age: &age, RR: "r", Score: 1, State: "active"}, m)
+
+	_, err = NewtestTableBuilder().Name("abcd").Build()
+	assert.EqualError(t, err, "testTable: required field age not set")
+
+	m2, err := NewtestTable2Builder().ID(uuid.Nil).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, &testTable2{}, m2)
+}
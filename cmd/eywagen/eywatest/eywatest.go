@@ -2,20 +2,30 @@ package eywatest
 
 import "github.com/google/uuid"
 
-//go:generate ../eywagen -types testTable,testTable2
+//go:generate ../eywagen -types testTable,testTable2 -gen-builder
 type testTable struct {
 	Name       string      `json:"name"`
 	Age        *int        `json:"age"`
-	ID         int         `json:"id,omitempty"`
+	ID         int         `json:"id,omitempty" constraint:"test_table_pkey" eywa:"pk"`
 	iD         int32       `json:"idd,omitempty"`
 	custom     *customType `json:"custom"`
 	testTable2 *testTable2 `json:"testTable2"`
 	JsonBCol   jsonbcol    `json:"jsonb_col"`
 	RR         R           `json:"r"`
+	Secret     string      `json:"secret,omitempty" eywa:"ignore"`
+	CreatedAt  *string     `json:"created_at,omitempty" eywa:"readonly"`
+	Score      float64     `json:"score"`
+	State      state       `json:"state" eywa:"enum"`
 }
 
 type R string
 
+type state string
+
+func (state) EnumType() string {
+	return "test_table_state_enum"
+}
+
 func (t testTable) ModelName() string {
 	return "test_table"
 }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/imperfect-fourth/eywa"
+)
+
+const (
+	modelStruct = `
+type %s struct {
+%s}
+
+func (%s) ModelName() string {
+	return "%s"
+}
+`
+	modelStructField = "\t%s %s `json:\"%s\"`\n"
+)
+
+// generateFromIntrospection introspects the schema served by client and
+// returns the source of a file in package pkgName with a model struct and
+// field constants for every table, or for the given tables only. Tables are
+// the object types returned as lists by root query fields of the same name.
+func generateFromIntrospection(ctx context.Context, client *eywa.Client, pkgName string, tables []string) ([]byte, error) {
+	result, err := client.Introspect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't introspect schema: %v", err)
+	}
+
+	objects := make(map[string]eywa.IntrospectionType, len(result.Types))
+	for _, t := range result.Types {
+		objects[t.Name] = t
+	}
+	root := objects[result.QueryType]
+	tableNames := make([]string, 0)
+	primaryKeys := make(map[string][]string)
+	for _, f := range root.Fields {
+		if t := listElem(f.Type); t != nil && t.Kind == "OBJECT" && t.Name == f.Name {
+			tableNames = append(tableNames, f.Name)
+		}
+		if table, ok := strings.CutSuffix(f.Name, "_by_pk"); ok {
+			for _, arg := range f.Args {
+				primaryKeys[table] = append(primaryKeys[table], arg.Name)
+			}
+		}
+	}
+	if len(tables) > 0 {
+		wanted := make(map[string]bool, len(tables))
+		for _, t := range tables {
+			wanted[t] = true
+		}
+		filtered := tableNames[:0]
+		for _, t := range tableNames {
+			if wanted[t] {
+				filtered = append(filtered, t)
+			}
+		}
+		tableNames = filtered
+	}
+	sort.Strings(tableNames)
+
+	buf := bytes.NewBufferString(genHeader)
+	buf.WriteString(pkgName)
+	buf.WriteString("\n\nimport (\n\t\"github.com/imperfect-fourth/eywa\"\n)\n")
+	for _, table := range tableNames {
+		writeIntrospectedModel(buf, objects[table], primaryKeys[table])
+	}
+	return buf.Bytes(), nil
+}
+
+func writeIntrospectedModel(buf *bytes.Buffer, obj eywa.IntrospectionType, primaryKey []string) {
+	typeName := goName(obj.Name)
+	structFields := bytes.NewBufferString("")
+	content := bytes.NewBufferString("")
+	scalarFields := bytes.NewBufferString("")
+	for _, f := range obj.Fields {
+		goType, varFunc, ok := goFieldType(f.Type)
+		if !ok {
+			continue
+		}
+		fieldName := fmt.Sprintf("%s_%s", typeName, goName(f.Name))
+		structFields.WriteString(fmt.Sprintf(modelStructField, goName(f.Name), goType, f.Name))
+		content.WriteString(fmt.Sprintf(modelFieldNameConst, fieldName, typeName, f.Name))
+		content.WriteString(fmt.Sprintf(modelFieldFunc, fieldName, goType, typeName, typeName, f.Name))
+		if base := strings.TrimPrefix(goType, "*"); base == "int" || base == "int64" || base == "int16" || base == "float64" {
+			for _, funcFormat := range []string{modelIncFunc, modelDecFunc} {
+				content.WriteString(fmt.Sprintf(funcFormat, fieldName, base, typeName, typeName, f.Name))
+			}
+		}
+		if varFunc != "" {
+			content.WriteString(fmt.Sprintf(modelScalarVarFunc, fieldName, goType, typeName, typeName, f.Name, fieldName, varFunc, goType))
+		}
+		scalarFields.WriteString(fmt.Sprintf("\t%s,\n", fieldName))
+	}
+	buf.WriteString(fmt.Sprintf(modelStruct, typeName, structFields.String(), typeName, obj.Name))
+	buf.WriteString("\n")
+	buf.WriteString(fmt.Sprintf(modelAssertion, typeName))
+	buf.WriteString(content.String())
+	if scalarFields.Len() > 0 {
+		buf.WriteString(fmt.Sprintf(modelFieldsVar, typeName, typeName, scalarFields.String()))
+	}
+	if len(primaryKey) > 0 {
+		pk := bytes.NewBufferString("")
+		for _, col := range primaryKey {
+			pk.WriteString(fmt.Sprintf("\t%s_%s,\n", typeName, goName(col)))
+		}
+		buf.WriteString(fmt.Sprintf(modelPrimaryKeyVar, typeName, typeName, pk.String()))
+	}
+}
+
+// listElem returns the element type of a [T!]! type, or nil if t is not a
+// list.
+func listElem(t eywa.IntrospectionTypeRef) *eywa.IntrospectionTypeRef {
+	ref := &t
+	if ref.Kind == "NON_NULL" {
+		ref = ref.OfType
+	}
+	if ref == nil || ref.Kind != "LIST" {
+		return nil
+	}
+	ref = ref.OfType
+	if ref != nil && ref.Kind == "NON_NULL" {
+		ref = ref.OfType
+	}
+	return ref
+}
+
+var scalarGoTypes = map[string]string{
+	"Int":      "int",
+	"Float":    "float64",
+	"String":   "string",
+	"Boolean":  "bool",
+	"bigint":   "int64",
+	"smallint": "int16",
+	"numeric":  "float64",
+	"float8":   "float64",
+	"json":     "map[string]interface{}",
+	"jsonb":    "map[string]interface{}",
+}
+
+var scalarVarFuncs = map[string]string{
+	"Int":      "eywa.IntVar",
+	"*Int":     "eywa.NullableIntVar",
+	"Float":    "eywa.FloatVar",
+	"*Float":   "eywa.NullableFloat",
+	"String":   "eywa.StringVar",
+	"*String":  "eywa.NullableStringVar",
+	"Boolean":  "eywa.BooleanVar",
+	"*Boolean": "eywa.NullableBooleanVar",
+}
+
+// goFieldType returns the Go type for a column of the given graphql type and
+// the eywa function for declaring a query variable of it, if the column type
+// is a builtin graphql scalar. Object fields (relationships) are skipped.
+func goFieldType(t eywa.IntrospectionTypeRef) (goType, varFunc string, ok bool) {
+	nullable := t.Kind != "NON_NULL"
+	ref := &t
+	if !nullable {
+		ref = ref.OfType
+	}
+	if elem := listElem(*ref); elem != nil {
+		if elem.Kind != "SCALAR" && elem.Kind != "ENUM" {
+			return "", "", false
+		}
+		return "[]" + scalarGoType(elem.Name), "", true
+	}
+	if ref.Kind != "SCALAR" && ref.Kind != "ENUM" {
+		return "", "", false
+	}
+	goType = scalarGoType(ref.Name)
+	if strings.HasPrefix(goType, "map") {
+		return goType, "", true
+	}
+	varKey := ref.Name
+	if nullable {
+		goType = "*" + goType
+		varKey = "*" + varKey
+	}
+	return goType, scalarVarFuncs[varKey], true
+}
+
+func scalarGoType(name string) string {
+	if goType, ok := scalarGoTypes[name]; ok {
+		return goType
+	}
+	// uuid, timestamptz, date, enums and other custom scalars are sent as
+	// strings.
+	return "string"
+}
+
+var initialisms = map[string]string{
+	"id":   "ID",
+	"url":  "URL",
+	"uuid": "UUID",
+	"json": "JSON",
+	"api":  "API",
+}
+
+// goName converts a snake_case graphql name to an exported Go identifier,
+// e.g. user_id to UserID.
+func goName(name string) string {
+	var buf strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		if initialism, ok := initialisms[strings.ToLower(part)]; ok {
+			buf.WriteString(initialism)
+			continue
+		}
+		buf.WriteString(strings.ToUpper(part[:1]))
+		buf.WriteString(part[1:])
+	}
+	if buf.Len() == 0 || (buf.String()[0] >= '0' && buf.String()[0] <= '9') {
+		return "X" + buf.String()
+	}
+	return buf.String()
+}
@@ -2,39 +2,213 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"go/token"
 	"go/types"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
 	re "regexp"
+	"sort"
 	"strings"
 
+	"github.com/imperfect-fourth/eywa"
 	"golang.org/x/tools/go/packages"
+	"gopkg.in/yaml.v3"
 )
 
 var (
 	typeNames  = flag.String("types", "", "comma-separated list of type names; must be set")
 	outputFile = flag.String("output-file", "eywa_generated.go", "output file path for generated file.")
+	pkgPath    = flag.String("package", ".", "import path of the package containing the types; defaults to the current directory.")
+	tagKey     = flag.String("tag-key", "json", "struct tag to read field names from, e.g. db. The names must match the json tag.")
+	buildTags  = flag.String("build-tags", "", "comma-separated list of build tags to load the package with.")
+	genBuilder = flag.Bool("gen-builder", false, "also generate a <Type>Builder with a setter per field for constructing models.")
+	verify     = flag.Bool("verify", false, "run go vet on the package of the output file and delete the file if it fails.")
+
+	fromIntrospection = flag.Bool("from-introspection", false, "generate model structs and fields from the schema of a Hasura endpoint instead of Go source; -types optionally limits the tables.")
+	endpoint          = flag.String("endpoint", "", "graphql endpoint to introspect with -from-introspection.")
+	adminSecret       = flag.String("admin-secret", "", "Hasura admin secret for -endpoint.")
+	packageName       = flag.String("package-name", "", "package name of the file generated with -from-introspection; defaults to the name of the output file's directory.")
 )
 
+const configFile = "eywa.yaml"
+
 func usage() {
 	fmt.Fprint(os.Stderr, "Usage:")
-	fmt.Fprint(os.Stderr, "\teywagen -types <comma separated list of type names>")
+	fmt.Fprint(os.Stderr, "\teywagen -types <comma separated list of type names> [-package <import path>] [-output-file <path>] [-tag-key <struct tag>] [-build-tags <comma separated list of tags>] [-gen-builder] [-verify]")
+	fmt.Fprint(os.Stderr, "\n\teywagen -from-introspection -endpoint <graphql endpoint> [-admin-secret <secret>] [-types <comma separated list of tables>] [-output-file <path>] [-package-name <name>] [-verify]")
+	fmt.Fprintf(os.Stderr, "\nFlags can also be set in %s in the current directory, keyed by flag name. Flags passed on the command line take precedence.\n", configFile)
+}
+
+// loadConfig sets the flags of fs that were not passed on the command line
+// from the yaml file at path, where keys are flag names. List values are
+// joined with commas, e.g. "types: [a, b]" is equivalent to "-types a,b". A
+// missing file is not an error.
+func loadConfig(path string, fs *flag.FlagSet) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var cfg map[string]interface{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("couldn't parse %s: %v", path, err)
+	}
+
+	passed := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+	for name, value := range cfg {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown key %q in %s", name, path)
+		}
+		if passed[name] {
+			continue
+		}
+		str := fmt.Sprint(value)
+		if list, ok := value.([]interface{}); ok {
+			items := make([]string, 0, len(list))
+			for _, item := range list {
+				items = append(items, fmt.Sprint(item))
+			}
+			str = strings.Join(items, ",")
+		}
+		if err := fs.Set(name, str); err != nil {
+			return fmt.Errorf("invalid value for %q in %s: %v", name, path, err)
+		}
+	}
+	return nil
+}
+
+var jsonTagPattern = fieldTagPattern("json")
+var tagPattern = jsonTagPattern
+var constraintTagPattern = re.MustCompile(`constraint:"([^"]+)"`)
+var eywaTagPattern = re.MustCompile(`eywa:"([^"]+)"`)
+
+// fieldTagPattern matches the struct tag that field names are read from.
+func fieldTagPattern(key string) *regexp.Regexp {
+	return re.MustCompile(fmt.Sprintf(`(?:^|\s)%s:"([^"]+)"`, re.QuoteMeta(key)))
+}
+
+// jsonFieldName returns the name encoding/json uses for a struct field with
+// the given name and tag.
+func jsonFieldName(name, tag string) string {
+	match := jsonTagPattern.FindStringSubmatch(tag)
+	if match == nil {
+		return name
+	}
+	if jsonName := strings.Split(match[1], ",")[0]; jsonName != "" {
+		return jsonName
+	}
+	return name
 }
 
-var tagPattern = re.MustCompile(`json:"([^"]+)"`)
+// eywaTagOptions returns the comma separated options of the eywa struct tag:
+// "ignore" skips the field, "pk" marks it as part of the primary key and
+// "readonly" skips the Field and Var helpers.
+func eywaTagOptions(tag string) map[string]bool {
+	opts := make(map[string]bool)
+	match := eywaTagPattern.FindStringSubmatch(tag)
+	if match == nil {
+		return opts
+	}
+	for _, opt := range strings.Split(match[1], ",") {
+		opts[strings.TrimSpace(opt)] = true
+	}
+	return opts
+}
+
+// addBuilderSetter writes the -gen-builder setter of a field to buf, and adds
+// the field to required if its json tag options don't include omitempty.
+// Unexported fields get no setter, as the builder is meant for constructing
+// models outside their package; they are left zero. A field named Build is an
+// error, as its setter would clash with the Build method.
+func addBuilderSetter(buf *bytes.Buffer, required *[]string, typeName, goName, goType, fieldName string, tagOpts []string) error {
+	if !token.IsExported(goName) {
+		return nil
+	}
+	if goName == "Build" {
+		return fmt.Errorf("field Build of %s clashes with the Build method of the generated builder", typeName)
+	}
+	buf.WriteString(fmt.Sprintf(modelBuilderSetter, typeName, goName, goType, typeName, goName, fieldName))
+	for _, opt := range tagOpts {
+		if opt == "omitempty" {
+			return nil
+		}
+	}
+	*required = append(*required, fmt.Sprintf("%q", fieldName))
+	return nil
+}
 
 const (
-	genHeader           = "// generated by eywa. DO NOT EDIT. Any changes will be overwritten.\npackage "
-	modelFieldNameConst = "const %s eywa.ModelFieldName[%s] = \"%s\"\n"
-	modelFieldFunc      = `
+	genHeader            = "// generated by eywa. DO NOT EDIT. Any changes will be overwritten.\npackage "
+	modelAssertion       = "var _ eywa.Model = (*%s)(nil)\n\n"
+	modelFieldNameConst  = "const %s eywa.ModelFieldName[%s] = \"%s\"\n"
+	modelConstraintConst = "const %sConstraint eywa.Constraint[%s] = \"%s\"\n"
+	modelFieldsVar       = "\nvar %sFields = []eywa.ModelFieldName[%s]{\n%s}\n"
+	modelPrimaryKeyVar   = "\nvar %sPrimaryKey = []eywa.ModelFieldName[%s]{\n%s}\n"
+	modelFieldFunc       = `
 func %sField(val %s) eywa.ModelField[%s] {
 	return eywa.ModelField[%s]{
 		Name: "%s",
 		Value: val,
 	}
 }
+`
+	modelIncFunc = `
+func %sInc(delta %s) eywa.ModelField[%s] {
+	return eywa.ModelField[%s]{
+		Name: "%s",
+		Value: delta,
+	}
+}
+`
+	modelDecFunc = `
+func %sDec(delta %s) eywa.ModelField[%s] {
+	return eywa.ModelField[%s]{
+		Name: "%s",
+		Value: -delta,
+	}
+}
+`
+	modelZeroFunc = `
+func %sZero() *%s {
+	return &%s{}
+}
+
+`
+	modelWithFunc = `
+func %sWith(fields ...eywa.ModelField[%s]) *%s {
+	m := &%s{}
+	for _, f := range fields {
+		var err error
+		switch f.Name {
+%s		default:
+			err = fmt.Errorf("%s has no field %%q", f.Name)
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
+	return m
+}
+`
+	modelWithCase    = "\t\tcase \"%s\":\n\t\t\tm.%s, err = eywa.FieldValue[%s](f)\n"
+	modelEnumVarFunc = `
+func %sEnumVar(val %s) eywa.ModelField[%s] {
+	return eywa.ModelField[%s]{
+		Name: "%s",
+		Value: eywa.QueryVar("%s", eywa.EnumValue(val)),
+	}
+}
 `
 	modelScalarVarFunc = `
 func %sVar(val %s) eywa.ModelField[%s] {
@@ -53,16 +227,43 @@ func %sVar[T interface{%s;eywa.TypedValue}](val %s) eywa.ModelField[%s] {
 }
 `
 
-	modelRelationshipNameFunc = `
-func %s(subField eywa.ModelFieldName[%s], subFields ...eywa.ModelFieldName[%s]) string {
-	buf := bytes.NewBuffer([]byte("%s {"))
-	buf.WriteString(string(subField))
-	for _, f := range subFields {
-		buf.WriteString("\n")
-		buf.WriteString(string(f))
+	modelBuilder = `
+type %sBuilder struct {
+	m   %s
+	set map[string]bool
+}
+
+func New%sBuilder() *%sBuilder {
+	return &%sBuilder{set: map[string]bool{}}
+}
+`
+	modelBuilderSetter = `
+func (b *%sBuilder) %s(val %s) *%sBuilder {
+	b.m.%s = val
+	b.set["%s"] = true
+	return b
+}
+`
+	modelBuilderBuild = `
+func (b *%sBuilder) Build() (*%s, error) {
+	for _, f := range []string{%s} {
+		if !b.set[f] {
+			return nil, fmt.Errorf("%s: required field %%s not set", f)
+		}
 	}
-	buf.WriteString("}")
-	return buf.String()
+	m := b.m
+	return &m, nil
+}
+`
+
+	modelRelationshipNameFunc = `
+func %s(subField eywa.ModelFieldName[%s], subFields ...eywa.ModelFieldName[%s]) eywa.ModelFieldName[%s] {
+	return eywa.GetWithRelationship[%s]("%s", subField, subFields...)
+}
+`
+	modelRelationshipWhereFunc = `
+func %sWhere(where *eywa.WhereExpr, subField eywa.ModelFieldName[%s], subFields ...eywa.ModelFieldName[%s]) eywa.ModelFieldName[%s] {
+	return eywa.GetWithRelationshipWhere[%s]("%s", where, subField, subFields...)
 }
 `
 )
@@ -70,17 +271,52 @@ func %s(subField eywa.ModelFieldName[%s], subFields ...eywa.ModelFieldName[%s])
 func main() {
 	flag.Usage = usage
 	flag.Parse()
+	if err := loadConfig(configFile, flag.CommandLine); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	if *fromIntrospection {
+		if err := runIntrospection(); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		if err := verifyOutput(*outputFile, ""); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 	if *typeNames == "" {
 		flag.Usage()
 		os.Exit(2)
 	}
+	tagPattern = fieldTagPattern(*tagKey)
 	types := strings.Split(*typeNames, ",")
 
-	pkg, err := loadPackage()
+	pkg, err := loadPackage(*pkgPath, *buildTags)
 	if err != nil {
 		panic(err)
 	}
 
+	contents, err := generate(pkg, types)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	if err := writeToFile(*outputFile, contents); err != nil {
+		fmt.Fprint(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	if err := verifyOutput(*outputFile, *buildTags); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+// generate returns the generated code for the model types typeNames of pkg.
+// The file belongs to pkg, which may be loaded from another directory with
+// -package.
+func generate(pkg *types.Package, typeNames []string) (*fileContent, error) {
 	header := bytes.NewBufferString(genHeader)
 	header.WriteString(pkg.Name())
 	header.WriteString("\n")
@@ -91,22 +327,80 @@ func main() {
 		imports:    bytes.NewBuffer([]byte{}),
 		content:    bytes.NewBufferString(""),
 	}
-	for _, t := range types {
-		parseType(t, pkg, contents)
+	for _, t := range typeNames {
+		if err := parseType(t, pkg, contents); err != nil {
+			return nil, err
+		}
 	}
 	if len(contents.importsMap) > 0 {
-		contents.imports.WriteString("\nimport (\n")
+		imports := make([]string, 0, len(contents.importsMap))
 		for pkgImport, ok := range contents.importsMap {
 			if ok {
-				contents.imports.WriteString(fmt.Sprintf("\t\"%s\"\n", pkgImport))
+				imports = append(imports, pkgImport)
 			}
 		}
+		sort.Strings(imports)
+		contents.imports.WriteString("\nimport (\n")
+		for _, pkgImport := range imports {
+			contents.imports.WriteString(fmt.Sprintf("\t\"%s\"\n", pkgImport))
+		}
 		contents.imports.WriteString(")\n\n")
 	}
-	if err := writeToFile(*outputFile, contents); err != nil {
-		fmt.Fprint(os.Stderr, err.Error())
-		os.Exit(1)
+	return contents, nil
+}
+
+// verifyOutput runs go vet on the package of the generated file if -verify is
+// set, and deletes the file if vet fails. The composites check is disabled
+// because the generated Var functions build their typed values with unkeyed
+// literals of a type parameter. Only the package of the file is vetted, not
+// ./..., since the generated code can't break other packages and their
+// problems shouldn't get the file deleted.
+func verifyOutput(path, tags string) error {
+	if !*verify {
+		return nil
+	}
+	args := []string{"vet", "-composites=false"}
+	if tags != "" {
+		args = append(args, "-tags", tags)
+	}
+	cmd := exec.Command("go", append(args, ".")...)
+	cmd.Dir = filepath.Dir(path)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if rmErr := os.Remove(path); rmErr != nil {
+		return fmt.Errorf("go vet failed: %v\n%s\ncouldn't delete %s: %v", err, out, path, rmErr)
+	}
+	return fmt.Errorf("go vet failed, deleted %s: %v\n%s", path, err, out)
+}
+
+func runIntrospection() error {
+	if *endpoint == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	client := eywa.NewClient(*endpoint, nil)
+	if *adminSecret != "" {
+		client = eywa.NewClientWithAdminSecret(*endpoint, *adminSecret)
+	}
+	pkgName := *packageName
+	if pkgName == "" {
+		dir, err := filepath.Abs(filepath.Dir(*outputFile))
+		if err != nil {
+			return err
+		}
+		pkgName = filepath.Base(dir)
+	}
+	var tables []string
+	if *typeNames != "" {
+		tables = strings.Split(*typeNames, ",")
 	}
+	src, err := generateFromIntrospection(context.Background(), client, pkgName, tables)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*outputFile, src, 0o644)
 }
 
 type fileContent struct {
@@ -118,29 +412,39 @@ type fileContent struct {
 
 var parsed = make(map[string]bool)
 
-func parseType(typeName string, pkg *types.Package, contents *fileContent) {
+// parseType writes the generated code for the model type typeName and the
+// models it has relationships with to contents. Types that aren't models are
+// skipped; fields that can't be generated are an error.
+func parseType(typeName string, pkg *types.Package, contents *fileContent) error {
 	if parsed[typeName] {
-		return
+		return nil
 	}
 	parsed[typeName] = true
 
 	typeObj := pkg.Scope().Lookup(typeName)
 	if typeObj == nil {
 		fmt.Printf("type %s not found in package, skipping...", typeName)
-		return
+		return nil
 	}
 	typeStruct, ok := typeObj.Type().Underlying().(*types.Struct)
 	if !ok {
 		fmt.Printf("type %s is not a struct, skipping...", typeName)
-		return
+		return nil
 	}
 	if types.NewMethodSet(types.NewPointer(typeObj.Type())).Lookup(pkg, "ModelName") == nil {
 		fmt.Printf("struct type %s does not implement eywa.Model interface, skipping...", typeName)
-		return
+		return nil
 	}
 
 	contents.content.WriteString("\n")
+	contents.content.WriteString(fmt.Sprintf(modelAssertion, typeName))
+	contents.content.WriteString(fmt.Sprintf(modelZeroFunc, typeName, typeName, typeName))
 	recurseParse := make([]string, 0, typeStruct.NumFields())
+	scalarFields := bytes.NewBufferString("")
+	primaryKey := bytes.NewBufferString("")
+	builderSetters := bytes.NewBufferString("")
+	withCases := bytes.NewBufferString("")
+	var requiredFields []string
 	for i := 0; i < typeStruct.NumFields(); i++ {
 		tag := tagPattern.FindStringSubmatch(typeStruct.Tag(i))
 		if tag == nil {
@@ -152,6 +456,24 @@ func parseType(typeName string, pkg *types.Package, contents *fileContent) {
 		}
 		fieldName := tagValue[0]
 		field := typeStruct.Field(i)
+		if jsonName := jsonFieldName(field.Name(), typeStruct.Tag(i)); jsonName != fieldName {
+			return fmt.Errorf("field %s of %s: %s name %q differs from json name %q; eywa decodes responses using the json tag", field.Name(), typeName, *tagKey, fieldName, jsonName)
+		}
+		opts := eywaTagOptions(typeStruct.Tag(i))
+		if opts["ignore"] {
+			continue
+		}
+		if opts["pk"] {
+			primaryKey.WriteString(fmt.Sprintf("\t%s_%s,\n", typeName, field.Name()))
+		}
+		if constraint := constraintTagPattern.FindStringSubmatch(typeStruct.Tag(i)); constraint != nil {
+			contents.content.WriteString(fmt.Sprintf(
+				modelConstraintConst,
+				fmt.Sprintf("%s_%s", typeName, field.Name()),
+				typeName,
+				constraint[1],
+			))
+		}
 		fieldType := field.Type()
 		typeSourcePkgName, fieldTypeNameFull := parseFieldTypeName(field.Type().String(), pkg.Path())
 		if typeSourcePkgName != "" {
@@ -186,12 +508,22 @@ func parseType(typeName string, pkg *types.Package, contents *fileContent) {
 		case *types.Pointer:
 			fieldMethodSet := types.NewMethodSet(fieldType)
 			if m := fieldMethodSet.Lookup(pkg, "ModelName"); m != nil && m.Type().String() == "func() string" {
-				contents.importsMap["bytes"] = true
 				contents.content.WriteString(fmt.Sprintf(
 					modelRelationshipNameFunc,
 					fmt.Sprintf("%s_%s", typeName, field.Name()),
 					fieldTypeName,
 					fieldTypeName,
+					typeName,
+					typeName,
+					fieldName,
+				))
+				contents.content.WriteString(fmt.Sprintf(
+					modelRelationshipWhereFunc,
+					fmt.Sprintf("%s_%s", typeName, field.Name()),
+					fieldTypeName,
+					fieldTypeName,
+					typeName,
+					typeName,
 					fieldName,
 				))
 				recurseParse = append(recurseParse, fieldTypeName)
@@ -202,6 +534,14 @@ func parseType(typeName string, pkg *types.Package, contents *fileContent) {
 					typeName,
 					fieldName,
 				))
+				scalarFields.WriteString(fmt.Sprintf("\t%s_%s,\n", typeName, field.Name()))
+				withCases.WriteString(fmt.Sprintf(modelWithCase, fieldName, field.Name(), fieldTypeNameFull))
+				if opts["readonly"] {
+					break
+				}
+				if err := addBuilderSetter(builderSetters, &requiredFields, typeName, field.Name(), fieldTypeNameFull, fieldName, tagValue[1:]); err != nil {
+					return err
+				}
 				contents.content.WriteString(fmt.Sprintf(
 					modelFieldFunc,
 					fmt.Sprintf("%s_%s", typeName, field.Name()),
@@ -242,6 +582,14 @@ func parseType(typeName string, pkg *types.Package, contents *fileContent) {
 				typeName,
 				fieldName,
 			))
+			scalarFields.WriteString(fmt.Sprintf("\t%s_%s,\n", typeName, field.Name()))
+			withCases.WriteString(fmt.Sprintf(modelWithCase, fieldName, field.Name(), fieldTypeNameFull))
+			if opts["readonly"] {
+				break
+			}
+			if err := addBuilderSetter(builderSetters, &requiredFields, typeName, field.Name(), fieldTypeNameFull, fieldName, tagValue[1:]); err != nil {
+				return err
+			}
 			contents.content.WriteString(fmt.Sprintf(
 				modelFieldFunc,
 				fmt.Sprintf("%s_%s", typeName, field.Name()),
@@ -250,6 +598,18 @@ func parseType(typeName string, pkg *types.Package, contents *fileContent) {
 				typeName,
 				fieldName,
 			))
+			if isIncrementable(fieldType) {
+				for _, funcFormat := range []string{modelIncFunc, modelDecFunc} {
+					contents.content.WriteString(fmt.Sprintf(
+						funcFormat,
+						fmt.Sprintf("%s_%s", typeName, field.Name()),
+						fieldTypeName,
+						typeName,
+						typeName,
+						fieldName,
+					))
+				}
+			}
 			if fieldScalarGqlType != "" {
 				contents.content.WriteString(fmt.Sprintf(
 					modelScalarVarFunc,
@@ -274,12 +634,81 @@ func parseType(typeName string, pkg *types.Package, contents *fileContent) {
 					fmt.Sprintf("%s_%s", typeName, field.Name()),
 				))
 			}
+			if opts["enum"] {
+				if err := checkEnumType(fieldType, pkg); err != nil {
+					return fmt.Errorf("field %s of %s: %v", field.Name(), typeName, err)
+				}
+				contents.content.WriteString(fmt.Sprintf(
+					modelEnumVarFunc,
+					fmt.Sprintf("%s_%s", typeName, field.Name()),
+					fieldTypeNameFull,
+					typeName,
+					typeName,
+					fieldName,
+					fmt.Sprintf("%s_%s", typeName, field.Name()),
+				))
+			}
 		}
 	}
+	contents.importsMap["fmt"] = true
+	contents.content.WriteString(fmt.Sprintf(modelWithFunc, typeName, typeName, typeName, typeName, withCases.String(), typeName))
+	if scalarFields.Len() > 0 {
+		contents.content.WriteString(fmt.Sprintf(modelFieldsVar, typeName, typeName, scalarFields.String()))
+	}
+	if primaryKey.Len() > 0 {
+		contents.content.WriteString(fmt.Sprintf(modelPrimaryKeyVar, typeName, typeName, primaryKey.String()))
+	}
+	if *genBuilder {
+		contents.content.WriteString(fmt.Sprintf(modelBuilder, typeName, typeName, typeName, typeName, typeName))
+		contents.content.WriteString(builderSetters.String())
+		contents.content.WriteString(fmt.Sprintf(
+			modelBuilderBuild,
+			typeName,
+			typeName,
+			strings.Join(requiredFields, ", "),
+			typeName,
+		))
+	}
 	for _, t := range recurseParse {
-		parseType(t, pkg, contents)
+		if err := parseType(t, pkg, contents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isIncrementable reports whether t is a signed integer or float type, i.e.
+// a column that _inc can increment and decrement with a negative value.
+func isIncrementable(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return false
 	}
+	info := basic.Info()
+	return info&types.IsFloat != 0 || (info&types.IsInteger != 0 && info&types.IsUnsigned == 0)
+}
 
+// isNamedString reports whether t is a named type with an underlying string
+// type, the Go representation of a Hasura enum.
+func isNamedString(t types.Type) bool {
+	if _, ok := t.(*types.Named); !ok {
+		return false
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.String
+}
+
+// checkEnumType returns an error if the type of a field tagged eywa:"enum"
+// can't be used with eywa.EnumValue, i.e. isn't a named string type with an
+// EnumType() string method.
+func checkEnumType(t types.Type, pkg *types.Package) error {
+	if !isNamedString(t) {
+		return fmt.Errorf("eywa:\"enum\" requires a named string type, got %s", t)
+	}
+	if m := types.NewMethodSet(t).Lookup(pkg, "EnumType"); m == nil || m.Type().String() != "func() string" {
+		return fmt.Errorf("eywa:\"enum\" requires type %s to have an EnumType() string method", t)
+	}
+	return nil
 }
 
 func writeToFile(filename string, contents *fileContent) error {
@@ -300,14 +729,20 @@ func writeToFile(filename string, contents *fileContent) error {
 	return nil
 }
 
-func loadPackage() (*types.Package, error) {
+func loadPackage(pattern, tags string) (*types.Package, error) {
 	cfg := &packages.Config{Mode: packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo, Tests: true}
-	pkgs, err := packages.Load(cfg, ".")
+	if tags != "" {
+		cfg.BuildFlags = []string{"-tags", tags}
+	}
+	pkgs, err := packages.Load(cfg, pattern)
 	if err != nil {
-		return nil, fmt.Errorf("couldn't load package: %v", err)
+		return nil, fmt.Errorf("couldn't load package %s: %v", pattern, err)
 	}
 	if packages.PrintErrors(pkgs) > 0 {
-		return nil, fmt.Errorf("package contains errors")
+		return nil, fmt.Errorf("package %s contains errors", pattern)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("package %s not found", pattern)
 	}
 	return pkgs[0].Types, nil
 }
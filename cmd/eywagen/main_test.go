@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/imperfect-fourth/eywa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), configFile)
+	err := os.WriteFile(path, []byte("types: [user, post]\noutput-file: models_eywa.go\n"), 0o644)
+	assert.NoError(t, err)
+
+	fs := flag.NewFlagSet("eywagen", flag.ContinueOnError)
+	types := fs.String("types", "", "")
+	output := fs.String("output-file", "eywa_generated.go", "")
+	pkg := fs.String("package", ".", "")
+	assert.NoError(t, fs.Parse(nil))
+
+	assert.NoError(t, loadConfig(path, fs))
+	assert.Equal(t, "user,post", *types)
+	assert.Equal(t, "models_eywa.go", *output)
+	assert.Equal(t, ".", *pkg)
+}
+
+func TestLoadConfigFlagsOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), configFile)
+	err := os.WriteFile(path, []byte("types: [user]\noutput-file: models_eywa.go\n"), 0o644)
+	assert.NoError(t, err)
+
+	fs := flag.NewFlagSet("eywagen", flag.ContinueOnError)
+	types := fs.String("types", "", "")
+	output := fs.String("output-file", "eywa_generated.go", "")
+	assert.NoError(t, fs.Parse([]string{"-types", "post"}))
+
+	assert.NoError(t, loadConfig(path, fs))
+	assert.Equal(t, "post", *types)
+	assert.Equal(t, "models_eywa.go", *output)
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	fs := flag.NewFlagSet("eywagen", flag.ContinueOnError)
+	assert.NoError(t, loadConfig(filepath.Join(t.TempDir(), configFile), fs))
+}
+
+func TestFieldTagPattern(t *testing.T) {
+	tag := `json:"userName,omitempty" db:"user_name" xdb:"other"`
+	assert.Equal(t, "userName,omitempty", fieldTagPattern("json").FindStringSubmatch(tag)[1])
+	assert.Equal(t, "user_name", fieldTagPattern("db").FindStringSubmatch(tag)[1])
+	assert.Nil(t, fieldTagPattern("sql").FindStringSubmatch(tag))
+}
+
+const introspectionResponseBody = `{"data": {"__schema": {
+	"queryType": {"name": "query_root"},
+	"types": [
+		{"kind": "OBJECT", "name": "query_root", "fields": [
+			{"name": "users", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "LIST", "ofType": {"kind": "NON_NULL", "ofType": {"kind": "OBJECT", "name": "users"}}}}},
+			{"name": "users_by_pk", "args": [{"name": "id"}], "type": {"kind": "OBJECT", "name": "users"}},
+			{"name": "users_aggregate", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "OBJECT", "name": "users_aggregate"}}}
+		]},
+		{"kind": "OBJECT", "name": "users", "fields": [
+			{"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "Int"}}},
+			{"name": "display_name", "args": [], "type": {"kind": "SCALAR", "name": "String"}},
+			{"name": "tags", "args": [], "type": {"kind": "LIST", "ofType": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "String"}}}},
+			{"name": "created_at", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "timestamptz"}}},
+			{"name": "posts", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "LIST", "ofType": {"kind": "NON_NULL", "ofType": {"kind": "OBJECT", "name": "posts"}}}}}
+		]}
+	]
+}}}`
+
+func TestGenerateFromIntrospection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(introspectionResponseBody))
+	}))
+	defer srv.Close()
+
+	src, err := generateFromIntrospection(context.Background(), eywa.NewClient(srv.URL, nil), "models", nil)
+	assert.NoError(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "eywa_generated.go", src, 0)
+	assert.NoError(t, err)
+
+	out := string(src)
+	assert.Contains(t, out, "type Users struct {\n\tID int `json:\"id\"`\n\tDisplayName *string `json:\"display_name\"`\n\tTags []string `json:\"tags\"`\n\tCreatedAt string `json:\"created_at\"`\n}")
+	assert.Contains(t, out, "func (Users) ModelName() string {\n\treturn \"users\"\n}")
+	assert.Contains(t, out, "const Users_DisplayName eywa.ModelFieldName[Users] = \"display_name\"")
+	assert.Contains(t, out, "eywa.QueryVar(\"Users_ID\", eywa.IntVar[int](val))")
+	assert.Contains(t, out, "var UsersPrimaryKey = []eywa.ModelFieldName[Users]{\n\tUsers_ID,\n}")
+	assert.Contains(t, out, "func Users_IDDec(delta int) eywa.ModelField[Users] {")
+	assert.NotContains(t, out, "Users_Posts")
+	assert.NotContains(t, out, "Users_CreatedAtVar")
+}
+
+func TestGoName(t *testing.T) {
+	assert.Equal(t, "UserID", goName("user_id"))
+	assert.Equal(t, "AuthUsers", goName("auth_users"))
+	assert.Equal(t, "X2fa", goName("2fa"))
+}
+
+// generateTypes runs parseType for typeNames on a package with the source src
+// and returns the generated code.
+func generateTypes(t *testing.T, src string, typeNames ...string) (string, error) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "models.go", src, 0)
+	assert.NoError(t, err)
+	pkg, err := (&types.Config{}).Check("example.com/models", fset, []*ast.File{file}, nil)
+	assert.NoError(t, err)
+
+	parsed = make(map[string]bool)
+	contents, err := generate(pkg, typeNames)
+	if err != nil {
+		return "", err
+	}
+	return contents.content.String(), nil
+}
+
+func TestParseTypeEnum(t *testing.T) {
+	out, err := generateTypes(t, `package models
+
+type state string
+
+func (state) EnumType() string { return "user_state_enum" }
+
+type user struct {
+	State state `+"`json:\"state\" eywa:\"enum\"`"+`
+}
+
+func (user) ModelName() string { return "users" }
+`, "user")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "func user_StateEnumVar(val state) eywa.ModelField[user] {")
+
+	_, err = generateTypes(t, `package models
+
+type state string
+
+type user struct {
+	State state `+"`json:\"state\" eywa:\"enum\"`"+`
+}
+
+func (user) ModelName() string { return "users" }
+`, "user")
+	assert.EqualError(t, err, `field State of user: eywa:"enum" requires type example.com/models.state to have an EnumType() string method`)
+
+	_, err = generateTypes(t, `package models
+
+type user struct {
+	State string `+"`json:\"state\" eywa:\"enum\"`"+`
+}
+
+func (user) ModelName() string { return "users" }
+`, "user")
+	assert.EqualError(t, err, `field State of user: eywa:"enum" requires a named string type, got string`)
+}
+
+func TestParseTypeEywaTags(t *testing.T) {
+	out, err := generateTypes(t, `package models
+
+type user struct {
+	Email     string `+"`json:\"email\" eywa:\"pk\"`"+`
+	Password  string `+"`json:\"password\" eywa:\"ignore\"`"+`
+	CreatedAt string `+"`json:\"created_at\" eywa:\"readonly\"`"+`
+}
+
+func (user) ModelName() string { return "users" }
+`, "user")
+	assert.NoError(t, err)
+
+	assert.Contains(t, out, "var userPrimaryKey = []eywa.ModelFieldName[user]{\n\tuser_Email,\n}")
+	assert.Contains(t, out, "func user_EmailField(val string) eywa.ModelField[user] {")
+
+	assert.NotContains(t, out, "Password")
+	assert.NotContains(t, out, "password")
+
+	assert.Contains(t, out, "const user_CreatedAt eywa.ModelFieldName[user] = \"created_at\"")
+	assert.Contains(t, out, "var userFields = []eywa.ModelFieldName[user]{\n\tuser_Email,\n\tuser_CreatedAt,\n}")
+	assert.NotContains(t, out, "user_CreatedAtField")
+	assert.NotContains(t, out, "user_CreatedAtVar")
+}
+
+func TestParseTypeTagKey(t *testing.T) {
+	key := *tagKey
+	*tagKey, tagPattern = "db", fieldTagPattern("db")
+	t.Cleanup(func() { *tagKey, tagPattern = key, fieldTagPattern(key) })
+
+	out, err := generateTypes(t, `package models
+
+type user struct {
+	Name string `+"`json:\"user_name\" db:\"user_name\"`"+`
+}
+
+func (user) ModelName() string { return "users" }
+`, "user")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "const user_Name eywa.ModelFieldName[user] = \"user_name\"")
+
+	_, err = generateTypes(t, `package models
+
+type user struct {
+	Name string `+"`json:\"userName\" db:\"user_name\"`"+`
+}
+
+func (user) ModelName() string { return "users" }
+`, "user")
+	assert.EqualError(t, err, `field Name of user: db name "user_name" differs from json name "userName"; eywa decodes responses using the json tag`)
+}
+
+// writeModule writes a module with the given files to a temporary directory
+// and changes into it for the duration of the test.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/models\n\ngo 1.22\n"
+	for name, src := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644))
+	}
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+	// the module isn't part of any workspace the tests run in
+	t.Setenv("GOWORK", "off")
+	return dir
+}
+
+func TestLoadPackageBuildTags(t *testing.T) {
+	writeModule(t, map[string]string{
+		"models.go":     "package models\n\ntype user struct{}\n",
+		"enterprise.go": "//go:build enterprise\n\npackage models\n\ntype auditLog struct{}\n",
+	})
+
+	pkg, err := loadPackage(".", "")
+	assert.NoError(t, err)
+	assert.NotNil(t, pkg.Scope().Lookup("user"))
+	assert.Nil(t, pkg.Scope().Lookup("auditLog"))
+
+	pkg, err = loadPackage(".", "enterprise")
+	assert.NoError(t, err)
+	assert.NotNil(t, pkg.Scope().Lookup("auditLog"))
+}
+
+func TestVerifyOutput(t *testing.T) {
+	*verify = true
+	defer func() { *verify = false }()
+
+	dir := writeModule(t, map[string]string{
+		"eywa_generated.go": "package models\n\nfunc userZero() int {\n\treturn 0\n}\n",
+	})
+	// only the package of the output file is vetted
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "broken"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken", "broken.go"), []byte("package broken\n\nfunc f() { undefined() }\n"), 0o644))
+	path := filepath.Join(dir, "eywa_generated.go")
+	assert.NoError(t, verifyOutput(path, ""))
+	assert.FileExists(t, path)
+
+	src := "package models\n\nimport \"fmt\"\n\nfunc userZero() string {\n\treturn fmt.Sprintf(\"%d\", \"zero\")\n}\n"
+	assert.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	err := verifyOutput(path, "")
+	assert.ErrorContains(t, err, "go vet failed, deleted "+path)
+	assert.NoFileExists(t, path)
+}
+
+func TestGenerateFromPackagePath(t *testing.T) {
+	writeModule(t, map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+	assert.NoError(t, os.Mkdir("db", 0o755))
+	src := "package db\n\ntype user struct {\n\tName string `json:\"name\"`\n}\n\nfunc (user) ModelName() string { return \"users\" }\n"
+	assert.NoError(t, os.WriteFile(filepath.Join("db", "models.go"), []byte(src), 0o644))
+
+	pkg, err := loadPackage("example.com/models/db", "")
+	assert.NoError(t, err)
+	parsed = make(map[string]bool)
+	contents, err := generate(pkg, []string{"user"})
+	assert.NoError(t, err)
+	assert.Equal(t, genHeader+"db\n", contents.header.String())
+	assert.Contains(t, contents.content.String(), "const user_Name eywa.ModelFieldName[user] = \"name\"")
+}
+
+func TestParseTypeBuilder(t *testing.T) {
+	*genBuilder = true
+	defer func() { *genBuilder = false }()
+
+	out, err := generateTypes(t, `package models
+
+type user struct {
+	Name  string `+"`json:\"name\"`"+`
+	token string `+"`json:\"token\"`"+`
+}
+
+func (user) ModelName() string { return "users" }
+`, "user")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "func (b *userBuilder) Name(val string) *userBuilder {")
+	assert.NotContains(t, out, "func (b *userBuilder) token(")
+	assert.Contains(t, out, `for _, f := range []string{"name"} {`)
+
+	_, err = generateTypes(t, `package models
+
+type job struct {
+	Build string `+"`json:\"build\"`"+`
+}
+
+func (job) ModelName() string { return "jobs" }
+`, "job")
+	assert.EqualError(t, err, "field Build of job clashes with the Build method of the generated builder")
+}
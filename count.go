@@ -0,0 +1,117 @@
+package eywa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// GetCount returns a query for the number of rows of model M matching its
+// arguments, using the <model>_aggregate root field.
+func GetCount[M Model, MP ModelPtr[M]]() CountQuery[M, ModelFieldName[M], ModelField[M]] {
+	return CountQuery[M, ModelFieldName[M], ModelField[M]]{
+		QuerySkeleton: QuerySkeleton[M, ModelFieldName[M], ModelField[M]]{
+			ModelName: QualifiedModelName(MP(new(M))),
+		},
+	}
+}
+
+type CountQuery[M Model, FN FieldName[M], F Field[M]] struct {
+	QuerySkeleton[M, FN, F]
+}
+
+func (cq CountQuery[M, FN, F]) Offset(n int) CountQuery[M, FN, F] {
+	cq.offset = (*offset)(&n)
+	return cq
+}
+
+func (cq CountQuery[M, FN, F]) Limit(n int) CountQuery[M, FN, F] {
+	cq.limit = (*limit)(&n)
+	return cq
+}
+
+func (cq CountQuery[M, FN, F]) Where(w *WhereExpr) CountQuery[M, FN, F] {
+	cq.where = &where{w}
+	return cq
+}
+
+// WhereCondition is like Where with the expression of a Condition on M.
+func (cq CountQuery[M, FN, F]) WhereCondition(c Condition[M]) CountQuery[M, FN, F] {
+	cq.where = &where{c.w}
+	return cq
+}
+
+func (cq CountQuery[M, FN, F]) marshalGQL() string {
+	return fmt.Sprintf(
+		"%s_aggregate%s {\naggregate {\ncount\n}\n}",
+		cq.ModelName,
+		cq.queryArgs.marshalGQL(),
+	)
+}
+
+func (cq CountQuery[M, FN, F]) Query() string {
+	return fmt.Sprintf(
+		"query get_%s_count%s {\n%s\n}",
+		cq.ModelName,
+		cq.variables().marshalGQL(),
+		cq.marshalGQL(),
+	)
+}
+
+// String returns the query formatted with indentation, for debugging.
+func (cq CountQuery[M, FN, F]) String() string {
+	return prettyPrint(cq.Query())
+}
+
+// Validate parses the generated query and returns an error if it is not
+// valid GraphQL, without sending it.
+func (cq CountQuery[M, FN, F]) Validate() error {
+	if err := cq.buildErr(); err != nil {
+		return err
+	}
+	return validateQuery(cq.Query())
+}
+
+func (cq CountQuery[M, FN, F]) Variables() map[string]interface{} {
+	return cq.variables().values()
+}
+
+// ExecRaw runs the query and returns the data field of the response without
+// decoding it.
+func (cq CountQuery[M, FN, F]) ExecRaw(ctx context.Context, client *Client) (json.RawMessage, error) {
+	if err := ValidateModelName(cq.ModelName); err != nil {
+		return nil, err
+	}
+	return client.Do(ctx, cq)
+}
+
+func (cq CountQuery[M, FN, F]) Exec(ctx context.Context, client *Client) (int, error) {
+	data, err := cq.ExecRaw(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	return cq.decode(data)
+}
+
+// ExecWithResponse runs the query and returns the count along with the http
+// status, headers and duration of the response.
+func (cq CountQuery[M, FN, F]) ExecWithResponse(ctx context.Context, client *Client) (*Response[int], error) {
+	if err := ValidateModelName(cq.ModelName); err != nil {
+		return nil, err
+	}
+	return execWithResponse(ctx, client, cq, cq.decode)
+}
+
+func (cq CountQuery[M, FN, F]) decode(data json.RawMessage) (int, error) {
+	type aggregateResponse struct {
+		Aggregate struct {
+			Count int `json:"count"`
+		} `json:"aggregate"`
+	}
+
+	respObj := map[string]aggregateResponse{}
+	if err := json.Unmarshal(data, &respObj); err != nil {
+		return 0, err
+	}
+	return respObj[fmt.Sprintf("%s_aggregate", cq.ModelName)].Aggregate.Count, nil
+}
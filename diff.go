@@ -0,0 +1,49 @@
+package eywa
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Diff compares two instances of a model and returns a field for every
+// column whose value differs, set to the value in new, e.g. to update only
+// the changed columns with Update[M]().Set(fields...). Columns are the
+// exported struct fields with a json tag. Pointers are compared by the values
+// they point to. Relationship fields and fields tagged eywa:"ignore" or
+// eywa:"readonly" are skipped.
+func Diff[M Model](old, new M) ([]ModelField[M], error) {
+	oldVal, newVal := reflect.ValueOf(old), reflect.ValueOf(new)
+	for oldVal.Kind() == reflect.Ptr {
+		if oldVal.IsNil() || newVal.IsNil() {
+			return nil, fmt.Errorf("eywa: can't diff nil %T", old)
+		}
+		oldVal, newVal = oldVal.Elem(), newVal.Elem()
+	}
+	if oldVal.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("eywa: can't diff %T: not a struct", old)
+	}
+
+	modelType := reflect.TypeOf((*Model)(nil)).Elem()
+	t := oldVal.Type()
+	var fields []ModelField[M]
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, ok := jsonFieldName(sf)
+		if !ok || hasTagOption(sf.Tag.Get("eywa"), "readonly") {
+			continue
+		}
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		if ft.Implements(modelType) || reflect.PointerTo(ft).Implements(modelType) {
+			continue
+		}
+
+		o, n := oldVal.Field(i).Interface(), newVal.Field(i).Interface()
+		if !reflect.DeepEqual(o, n) {
+			fields = append(fields, ModelField[M]{Name: name, Value: n})
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,64 @@
+package eywa
+
+import (
+	"errors"
+	"regexp"
+)
+
+// ConstraintViolationError is returned when Hasura rejects a mutation
+// because it violates a Postgres constraint, i.e. the error code is
+// constraint-violation or data-exception. Detect it with errors.As, or with a
+// type assertion if the response had a single error:
+//
+//	if cvErr, ok := err.(*eywa.ConstraintViolationError); ok { ... }
+type ConstraintViolationError struct {
+	// ConstraintName is the name of the violated constraint, if Postgres
+	// reported it.
+	ConstraintName string
+	// Detail is the error message from Hasura.
+	Detail string
+	// Code is the Hasura error code.
+	Code string
+}
+
+func (e *ConstraintViolationError) Error() string {
+	return e.Detail
+}
+
+var constraintNamePattern = regexp.MustCompile(`constraint "([^"]+)"`)
+
+// graphqlErrorsToError converts the errors of a graphql response to a single
+// error, or nil if there are none.
+func graphqlErrorsToError(gqlErrors []graphqlError) error {
+	if len(gqlErrors) == 0 {
+		return nil
+	}
+	errs := make([]error, 0, len(gqlErrors))
+	for _, e := range gqlErrors {
+		errs = append(errs, e.toError())
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// toError returns a ConstraintViolationError for constraint violations and an
+// EywaError otherwise.
+func (e graphqlError) toError() error {
+	eErr := EywaError{
+		Message:    e.Message,
+		Extensions: e.Extensions,
+	}
+	if eErr.IsConstraintViolation() {
+		cvErr := &ConstraintViolationError{
+			Detail: e.Message,
+			Code:   eErr.Code(),
+		}
+		if match := constraintNamePattern.FindStringSubmatch(e.Message); match != nil {
+			cvErr.ConstraintName = match[1]
+		}
+		return cvErr
+	}
+	return eErr
+}
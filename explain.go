@@ -0,0 +1,61 @@
+package eywa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ExplainResponse is the Postgres query plan Hasura generates for a query.
+type ExplainResponse struct {
+	// Plan is the output of EXPLAIN for the generated SQL, one plan node per
+	// line.
+	Plan string
+	// SQL is the SQL statement Hasura generated for the query.
+	SQL string
+}
+
+type explainRequest struct {
+	Query graphqlRequest `json:"query"`
+}
+
+type explainField struct {
+	Field string   `json:"field"`
+	SQL   string   `json:"sql"`
+	Plan  []string `json:"plan"`
+}
+
+// Explain asks Hasura for the query plan of q without running it. The request
+// is sent to the explain endpoint next to the graphql endpoint, i.e.
+// <endpoint>/explain, and requires admin access. Client middlewares are not
+// applied to explain requests.
+func (c *Client) Explain(ctx context.Context, q Queryable) (*ExplainResponse, error) {
+	if b, ok := q.(builder); ok {
+		if err := b.buildErr(); err != nil {
+			return nil, err
+		}
+	}
+	reqObj := explainRequest{
+		Query: graphqlRequest{
+			Query:     q.Query(),
+			Variables: q.Variables(),
+		},
+	}
+	respBytes, err := c.postJSON(c.withRequestID(ctx), strings.TrimRight(c.endpoint, "/")+"/explain", &reqObj)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []explainField
+	if err := json.NewDecoder(respBytes).Decode(&fields); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("empty explain response")
+	}
+	return &ExplainResponse{
+		Plan: strings.Join(fields[0].Plan, "\n"),
+		SQL:  fields[0].SQL,
+	}, nil
+}
@@ -2,10 +2,14 @@ package eywa
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 type graphqlRequest struct {
@@ -22,17 +26,128 @@ type Model interface {
 	ModelName() string
 }
 
+// SchemaModel is implemented by models whose table is not in the public
+// Postgres schema.
+type SchemaModel interface {
+	Model
+	// ModelSchema returns the Postgres schema of the model's table. An empty
+	// string means "public".
+	ModelSchema() string
+}
+
+// QualifiedModelName returns the name Hasura uses for the table of m in root
+// field and type names. For a SchemaModel in a schema other than public, this
+// is the table name prefixed with the schema, e.g. auth_users, which is
+// Hasura's default naming for tables outside the public schema.
+func QualifiedModelName(m Model) string {
+	if sm, ok := m.(SchemaModel); ok {
+		if schema := sm.ModelSchema(); schema != "" && schema != "public" {
+			return fmt.Sprintf("%s_%s", schema, m.ModelName())
+		}
+	}
+	return m.ModelName()
+}
+
+// AssertModel panics if neither T nor *T implements Model. Call it from an
+// init function to catch a misspelled ModelName method at startup; code
+// generated by eywagen asserts this at compile time instead.
+func AssertModel[T any]() {
+	var m T
+	if _, ok := any(m).(Model); ok {
+		return
+	}
+	if _, ok := any(&m).(Model); ok {
+		return
+	}
+	panic(fmt.Sprintf("eywa: %T does not implement eywa.Model (missing method ModelName() string)", m))
+}
+
+var modelNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// ValidateModelName returns an error if name, as returned by a model's
+// ModelName method, is not a lowercase identifier that can be safely used in
+// root field names such as update_<name>.
+func ValidateModelName(name string) error {
+	if !modelNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid model name %q: must match %s", name, modelNamePattern)
+	}
+	return nil
+}
+
 type ModelPtr[T Model] interface {
 	*T
 	Model
 }
 
 type ModelFieldName[M Model] string
+
+// String returns the field name as a plain string.
+func (fn ModelFieldName[M]) String() string {
+	return string(fn)
+}
+
+// Asc orders by the field in ascending order. It is shorthand for
+// Asc[M](fn), e.g. Get[User]().OrderBy(User_Name.Asc()).
+func (fn ModelFieldName[M]) Asc() OrderByExpr {
+	return Asc[M](fn)
+}
+
+// Desc orders by the field in descending order. It is shorthand for
+// Desc[M](fn).
+func (fn ModelFieldName[M]) Desc() OrderByExpr {
+	return Desc[M](fn)
+}
+
 type FieldName[M Model] interface {
 	string | ModelFieldName[M]
 }
 type FieldNameArr[M Model, FN FieldName[M]] []FN
 
+// Alias returns the selection of field under the name alias, e.g.
+// "full_name: name". The response has the field's value under alias, so M
+// needs a struct field with alias as its json tag for Exec to decode it.
+func Alias[M Model](alias string, field ModelFieldName[M]) ModelFieldName[M] {
+	return ModelFieldName[M](fmt.Sprintf("%s: %s", alias, field))
+}
+
+// GetWithRelationship returns the selection of a relationship field of
+// Parent together with the given fields of the related Child model, e.g.
+// "orders {id\ntotal}". Pass the result to Select on a Parent query to load
+// the related rows in the same request instead of querying per parent.
+func GetWithRelationship[Parent Model, Child Model](parentField ModelFieldName[Parent], childField ModelFieldName[Child], childFields ...ModelFieldName[Child]) ModelFieldName[Parent] {
+	buf := bytes.NewBufferString(string(parentField))
+	buf.WriteString(" {")
+	buf.WriteString(string(childField))
+	for _, f := range childFields {
+		buf.WriteString("\n")
+		buf.WriteString(string(f))
+	}
+	buf.WriteString("}")
+	return ModelFieldName[Parent](buf.String())
+}
+
+// GetWithRelationshipWhere is like GetWithRelationship, but only selects the
+// related rows matching where, e.g. "orders(where: {paid: {_eq: true}}) {id}".
+// The selection is a plain field name, which can't carry query variables to
+// the query, so it panics if where uses a query variable or fails to build.
+func GetWithRelationshipWhere[Parent Model, Child Model](parentField ModelFieldName[Parent], where *WhereExpr, childField ModelFieldName[Child], childFields ...ModelFieldName[Child]) ModelFieldName[Parent] {
+	if vars := where.queryVars(); len(vars) > 0 {
+		panic(fmt.Sprintf("eywa: where of relationship %s uses query variable $%s; use a value instead", parentField, vars[0].name))
+	}
+	if err := where.buildErr(); err != nil {
+		panic(fmt.Sprintf("eywa: where of relationship %s: %v", parentField, err))
+	}
+	return GetWithRelationship[Parent](
+		ModelFieldName[Parent](fmt.Sprintf("%s(where: %s)", parentField, where.String())),
+		childField,
+		childFields...,
+	)
+}
+
+// Constraint is the name of a Postgres constraint on the table of model M,
+// e.g. for use as an on_conflict target.
+type Constraint[M Model] string
+
 func (fa FieldNameArr[M, FN]) marshalGQL() string {
 	buf := bytes.NewBufferString("")
 	for i, f := range fa {
@@ -53,6 +168,10 @@ func (f RawField) GetName() string {
 	return f.Name
 }
 func (f RawField) GetValue() string {
+	if var_, ok := f.Value.(queryVar); ok {
+		return fmt.Sprintf("$%s", var_.name)
+	}
+
 	if val, ok := f.Value.(gqlMarshaler); ok {
 		return val.marshalGQL()
 	}
@@ -101,6 +220,12 @@ func (f ModelField[M]) GetRawValue() interface{} {
 	return f.Value
 }
 
+// String returns the field as it appears in a query, e.g. name: "abcd", for
+// debugging.
+func (f ModelField[M]) String() string {
+	return fmt.Sprintf("%s: %s", f.GetName(), f.GetValue())
+}
+
 type Field[M Model] interface {
 	RawField | ModelField[M]
 	GetName() string
@@ -139,6 +264,41 @@ type QuerySkeleton[M Model, FN FieldName[M], F Field[M]] struct {
 	queryArgs[M, FN, F]
 }
 
+// builder is implemented by queries that can fail to build, e.g. because a
+// variable name is used with different values. Client.Do returns the error
+// without sending the query.
+type builder interface {
+	buildErr() error
+}
+
+// queryVariables returns the query variables used by the arguments of the
+// query, and an error if a variable name is used with different values.
+func (qs QuerySkeleton[M, FN, F]) queryVariables() (queryVarArr, error) {
+	vars := append(queryVarArr{}, qs.queryVars...)
+	if qs.where != nil {
+		vars = append(vars, qs.where.queryVars()...)
+	}
+	return vars.merge()
+}
+
+// variables returns the query variables used by the arguments of the query.
+// Of a variable used with different values only the first is kept; buildErr
+// reports the conflict.
+func (qs QuerySkeleton[M, FN, F]) variables() queryVarArr {
+	vars, _ := qs.queryVariables()
+	return vars
+}
+
+func (qs QuerySkeleton[M, FN, F]) buildErr() error {
+	if _, err := qs.queryVariables(); err != nil {
+		return err
+	}
+	if qs.where != nil {
+		return qs.where.buildErr()
+	}
+	return nil
+}
+
 func (qs QuerySkeleton[M, FN, F]) marshalGQL() string {
 	return fmt.Sprintf("%s%s", qs.ModelName, qs.queryArgs.marshalGQL())
 }
@@ -146,7 +306,7 @@ func (qs QuerySkeleton[M, FN, F]) marshalGQL() string {
 func Get[M Model, MP ModelPtr[M]]() GetQueryBuilder[M, ModelFieldName[M], ModelField[M]] {
 	return GetQueryBuilder[M, ModelFieldName[M], ModelField[M]]{
 		QuerySkeleton: QuerySkeleton[M, ModelFieldName[M], ModelField[M]]{
-			ModelName: (*new(M)).ModelName(),
+			ModelName: QualifiedModelName(MP(new(M))),
 			//			fields:    append(fields, field),
 		},
 	}
@@ -154,6 +314,67 @@ func Get[M Model, MP ModelPtr[M]]() GetQueryBuilder[M, ModelFieldName[M], ModelF
 
 type GetQueryBuilder[M Model, FN FieldName[M], F Field[M]] struct {
 	QuerySkeleton[M, FN, F]
+	rawVars map[string]interface{}
+	strict  bool
+	cached  string
+}
+
+// WithCache caches the query response in Hasura for ttlSeconds by adding the
+// @cached directive to the query. Query caching is only available on Hasura
+// Cloud and Enterprise.
+func (sq GetQueryBuilder[M, FN, F]) WithCache(ttlSeconds int) GetQueryBuilder[M, FN, F] {
+	sq.cached = fmt.Sprintf(" @cached(ttl: %d)", ttlSeconds)
+	return sq
+}
+
+// WithCacheRefresh adds @cached(refresh: true) to the query, making Hasura
+// skip the cached response and cache the fresh one.
+func (sq GetQueryBuilder[M, FN, F]) WithCacheRefresh() GetQueryBuilder[M, FN, F] {
+	sq.cached = " @cached(refresh: true)"
+	return sq
+}
+
+// StrictSelect makes Exec and Validate return an error, without sending the
+// query, if a selected field is not a json tagged field of M. This catches
+// typos in raw field names. Selections added with SelectExpr are not
+// checked.
+func (sq GetQueryBuilder[M, FN, F]) StrictSelect() GetQueryBuilder[M, FN, F] {
+	sq.strict = true
+	return sq
+}
+
+// WithVariables adds variables to the query outside the typed QueryVar
+// system, for use with $name references in raw strings passed to Select or
+// Where. Values implementing TypedValue are also declared in the query
+// header; other values are only sent, and the caller is responsible for
+// declaring them. Variables set through the builder take precedence.
+func (sq GetQueryBuilder[M, FN, F]) WithVariables(vars map[string]interface{}) GetQueryBuilder[M, FN, F] {
+	rawVars := make(map[string]interface{}, len(sq.rawVars)+len(vars))
+	for name, val := range sq.rawVars {
+		rawVars[name] = val
+	}
+	for name, val := range vars {
+		rawVars[name] = val
+	}
+	sq.rawVars = rawVars
+	return sq
+}
+
+// variables returns the query variables including the typed raw variables
+// set with WithVariables, ordered by name.
+func (sq GetQueryBuilder[M, FN, F]) variables() queryVarArr {
+	vars := sq.QuerySkeleton.variables()
+	names := make([]string, 0, len(sq.rawVars))
+	for name, val := range sq.rawVars {
+		if _, ok := val.(TypedValue); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		vars = append(vars, queryVar{name, sq.rawVars[name].(TypedValue)})
+	}
+	return vars.dedupe()
 }
 
 func (sq GetQueryBuilder[M, FN, F]) DistinctOn(f FN) GetQueryBuilder[M, FN, F] {
@@ -166,22 +387,50 @@ func (sq GetQueryBuilder[M, FN, F]) Offset(n int) GetQueryBuilder[M, FN, F] {
 	return sq
 }
 
+// Limit limits the number of returned rows to n. Limit(0) omits the limit
+// argument, the same as UnsetLimit, rather than sending limit: 0, which
+// Hasura answers with no rows.
 func (sq GetQueryBuilder[M, FN, F]) Limit(n int) GetQueryBuilder[M, FN, F] {
+	if n == 0 {
+		return sq.UnsetLimit()
+	}
 	sq.limit = (*limit)(&n)
 	return sq
 }
 
+// UnsetLimit removes a limit set with Limit, e.g. on a copy of a shared
+// builder.
+func (sq GetQueryBuilder[M, FN, F]) UnsetLimit() GetQueryBuilder[M, FN, F] {
+	sq.limit = nil
+	return sq
+}
+
 func (sq GetQueryBuilder[M, FN, F]) OrderBy(o ...OrderByExpr) GetQueryBuilder[M, FN, F] {
 	orderByArr := orderBy(o)
 	sq.orderBy = &orderByArr
 	return sq
 }
 
+// ForUpdate locks the selected rows, e.g. for job queues. mode is "",
+// "skip_locked" or "nowait". Support for the for argument depends on the
+// Hasura version, so the mode is passed through as is.
+func (sq GetQueryBuilder[M, FN, F]) ForUpdate(mode string) GetQueryBuilder[M, FN, F] {
+	fu := forUpdate(mode)
+	sq.forUpdate = &fu
+	return sq
+}
+
 func (sq GetQueryBuilder[M, FN, F]) Where(w *WhereExpr) GetQueryBuilder[M, FN, F] {
 	sq.where = &where{w}
 	return sq
 }
 
+// WhereCondition is like Where with the expression of a Condition on M.
+func (sq GetQueryBuilder[M, FN, F]) WhereCondition(c Condition[M]) GetQueryBuilder[M, FN, F] {
+	sq.where = &where{c.w}
+	return sq
+}
+
 func (sq GetQueryBuilder[M, FN, F]) marshalGQL() string {
 	return sq.QuerySkeleton.marshalGQL()
 }
@@ -193,55 +442,169 @@ func (sq GetQueryBuilder[M, FN, F]) Select(field FN, fields ...FN) GetQuery[M, F
 	}
 }
 
+// ErrNoFields is returned without sending the request for a query or
+// mutation built with SelectFields and an empty list of fields.
+var ErrNoFields = errors.New("eywa: no fields selected")
+
+// SelectFields is like Select with the fields in a slice, e.g. a generated
+// <Type>Fields list or the fields of a SelectSet. The query fails with
+// ErrNoFields if fields is empty and no SelectExpr is added.
+func (sq GetQueryBuilder[M, FN, F]) SelectFields(fields []FN) GetQuery[M, FN, F] {
+	return GetQuery[M, FN, F]{
+		sq:     &sq,
+		fields: append([]FN{}, fields...),
+	}
+}
+
 type GetQuery[M Model, FN FieldName[M], F Field[M]] struct {
 	sq     *GetQueryBuilder[M, FN, F]
 	fields []FN
+	exprs  []string
+}
+
+// SelectExpr adds raw selections that aren't model fields, such as Hasura
+// computed fields, alongside the fields passed to Select.
+func (sq GetQuery[M, FN, F]) SelectExpr(exprs ...string) GetQuery[M, FN, F] {
+	sq.exprs = append(append([]string{}, sq.exprs...), exprs...)
+	return sq
 }
 
 func (sq GetQuery[M, FN, F]) marshalGQL() string {
+	selection := FieldNameArr[M, FN](sq.fields).marshalGQL()
+	for _, e := range sq.exprs {
+		selection += "\n" + e
+	}
 	return fmt.Sprintf(
 		"%s {\n%s\n}",
 		sq.sq.marshalGQL(),
-		FieldNameArr[M, FN](sq.fields).marshalGQL(),
+		selection,
 	)
 }
 
 func (sq GetQuery[M, FN, F]) Query() string {
 	return fmt.Sprintf(
-		"query get_%s {\n%s\n}",
+		"query get_%s%s%s {\n%s\n}",
 		sq.sq.ModelName,
+		sq.sq.variables().marshalGQL(),
+		sq.sq.cached,
 		sq.marshalGQL(),
 	)
 }
 
-func (sq GetQuery[M, FN, F]) Variables() map[string]interface{} {
+// String returns the query formatted with indentation, for debugging.
+func (sq GetQuery[M, FN, F]) String() string {
+	return prettyPrint(sq.Query())
+}
+
+func (sq GetQuery[M, FN, F]) buildErr() error {
+	if len(sq.fields) == 0 && len(sq.exprs) == 0 {
+		return ErrNoFields
+	}
+	return sq.sq.buildErr()
+}
+
+// Validate parses the generated query and returns an error if it is not
+// valid GraphQL, without sending it. Call it at startup to fail early.
+func (sq GetQuery[M, FN, F]) Validate() error {
+	if err := sq.buildErr(); err != nil {
+		return err
+	}
+	if err := sq.checkFields(); err != nil {
+		return err
+	}
+	return validateQuery(sq.Query())
+}
+
+// checkFields returns an error for the first selected field that isn't a
+// field of M, if StrictSelect was set.
+func (sq GetQuery[M, FN, F]) checkFields() error {
+	if !sq.sq.strict {
+		return nil
+	}
+	known := modelFieldNames(reflect.TypeOf((*M)(nil)).Elem())
+	for _, f := range sq.fields {
+		name := strings.TrimSpace(string(f))
+		if i := strings.IndexAny(name, " ({\n"); i >= 0 {
+			// relationship selection, e.g. "orders {id}", or alias, e.g.
+			// "full_name: name"
+			name = strings.TrimSuffix(name[:i], ":")
+		}
+		if !known[name] {
+			return fmt.Errorf("unknown field %q selected on %s", name, sq.sq.ModelName)
+		}
+	}
 	return nil
 }
 
-func (sq GetQuery[M, FN, F]) Exec(client *Client) ([]M, error) {
-	respBytes, err := client.do(sq)
-	if err != nil {
-		return nil, err
+func (sq GetQuery[M, FN, F]) Variables() map[string]interface{} {
+	vars := sq.sq.variables().values()
+	for name, val := range sq.sq.rawVars {
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		if vars == nil {
+			vars = make(map[string]interface{}, len(sq.sq.rawVars))
+		}
+		vars[name] = val
 	}
+	return vars
+}
 
-	type graphqlResponse struct {
-		Data   map[string][]M `json:"data"`
-		Errors []graphqlError `json:"errors"`
+// ExecRaw runs the query and returns the data field of the response without
+// decoding it.
+func (sq GetQuery[M, FN, F]) ExecRaw(ctx context.Context, client *Client) (json.RawMessage, error) {
+	if err := ValidateModelName(sq.sq.ModelName); err != nil {
+		return nil, err
+	}
+	if err := sq.checkFields(); err != nil {
+		return nil, err
 	}
+	return client.Do(ctx, sq)
+}
 
-	respObj := graphqlResponse{}
-	err = json.NewDecoder(respBytes).Decode(&respObj)
+// Exec runs the query and returns the rows. If the query succeeds, the result
+// is non-nil even if Hasura returns no rows or null.
+func (sq GetQuery[M, FN, F]) Exec(ctx context.Context, client *Client) ([]M, error) {
+	data, err := sq.ExecRaw(ctx, client)
 	if err != nil {
 		return nil, err
 	}
+	return sq.decode(data)
+}
 
-	if len(respObj.Errors) > 0 {
-		gqlErrs := make([]error, 0, len(respObj.Errors))
-		for _, e := range respObj.Errors {
-			gqlErrs = append(gqlErrs, errors.New(e.Message))
-		}
-		return nil, errors.Join(gqlErrs...)
+// ExecWithResponse runs the query and returns the rows along with the http
+// status, headers and duration of the response.
+func (sq GetQuery[M, FN, F]) ExecWithResponse(ctx context.Context, client *Client) (*Response[[]M], error) {
+	if err := ValidateModelName(sq.sq.ModelName); err != nil {
+		return nil, err
+	}
+	if err := sq.checkFields(); err != nil {
+		return nil, err
 	}
+	return execWithResponse(ctx, client, sq, sq.decode)
+}
 
-	return respObj.Data[sq.sq.ModelName], nil
+// Explain returns the Postgres query plan Hasura generates for the query,
+// without running it. See Client.Explain.
+func (sq GetQuery[M, FN, F]) Explain(ctx context.Context, client *Client) (*ExplainResponse, error) {
+	if err := ValidateModelName(sq.sq.ModelName); err != nil {
+		return nil, err
+	}
+	if err := sq.checkFields(); err != nil {
+		return nil, err
+	}
+	return client.Explain(ctx, sq)
+}
+
+// decode returns the rows in data. A null or missing result is returned as an
+// empty slice, never nil.
+func (sq GetQuery[M, FN, F]) decode(data json.RawMessage) ([]M, error) {
+	respObj := map[string][]M{}
+	if err := json.Unmarshal(data, &respObj); err != nil {
+		return nil, err
+	}
+	if rows := respObj[sq.sq.ModelName]; rows != nil {
+		return rows, nil
+	}
+	return []M{}, nil
 }
@@ -0,0 +1,83 @@
+// Package eywatest provides helpers for testing code that uses eywa without
+// a running Hasura instance.
+package eywatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/imperfect-fourth/eywa"
+)
+
+// Request is a graphql request recorded by a client returned by NoopClient.
+type Request struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// Recorder holds the requests sent by a client returned by NoopClient. It is
+// safe for concurrent use.
+type Recorder struct {
+	mu       sync.Mutex
+	requests []Request
+}
+
+// Requests returns the requests recorded so far, in the order they were
+// sent.
+func (r *Recorder) Requests() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Request{}, r.requests...)
+}
+
+// Queries returns the query strings recorded so far.
+func (r *Recorder) Queries() []string {
+	requests := r.Requests()
+	queries := make([]string, 0, len(requests))
+	for _, req := range requests {
+		queries = append(queries, req.Query)
+	}
+	return queries
+}
+
+func (r *Recorder) record(req Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+}
+
+// NoopClient returns a client that doesn't make any network calls. Every
+// request is recorded in the returned Recorder and answered with a 200
+// response with an empty data object, so Exec returns no rows.
+func NoopClient() (*eywa.Client, *Recorder) {
+	rec := &Recorder{}
+	client := eywa.NewClient("http://eywa.noop/v1/graphql", &eywa.ClientOpts{
+		HttpClient: &http.Client{Transport: &noopTransport{rec}},
+	})
+	return client, rec
+}
+
+type noopTransport struct {
+	rec *Recorder
+}
+
+func (t *noopTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body Request
+	if req.Body != nil {
+		defer req.Body.Close()
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+	}
+	t.rec.record(body)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(`{"data": {}}`)),
+		Request:    req,
+	}, nil
+}
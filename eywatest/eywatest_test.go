@@ -0,0 +1,31 @@
+package eywatest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/imperfect-fourth/eywa"
+	"github.com/stretchr/testify/assert"
+)
+
+type testTable struct {
+	Name string `json:"name"`
+}
+
+func (t testTable) ModelName() string {
+	return "test_table"
+}
+
+func TestNoopClient(t *testing.T) {
+	client, rec := NoopClient()
+
+	q := eywa.Get[testTable]().Where(
+		eywa.Eq[testTable](eywa.ModelField[testTable]{Name: "name", Value: eywa.QueryVar("name", eywa.StringVar("abc"))}),
+	).Select("name")
+	resp, err := q.Exec(context.Background(), client)
+	assert.NoError(t, err)
+	assert.Empty(t, resp)
+
+	assert.Equal(t, []Request{{Query: q.Query(), Variables: map[string]interface{}{"name": "abc"}}}, rec.Requests())
+	assert.Equal(t, []string{q.Query()}, rec.Queries())
+}
@@ -0,0 +1,164 @@
+package eywa
+
+import (
+	"strings"
+)
+
+const indentUnit = "  "
+
+// prettyPrint formats a query string generated by eywa with two-space
+// indentation for nested selection sets and one argument per line inside
+// parentheses. It is not a general GraphQL formatter: values inside an
+// argument (object and list literals, strings) are copied verbatim.
+func prettyPrint(query string) string {
+	p := &printer{src: query}
+	p.print()
+	return strings.TrimRight(p.buf.String(), "\n")
+}
+
+type printer struct {
+	src       string
+	pos       int
+	depth     int
+	lineStart bool
+	buf       strings.Builder
+}
+
+func (p *printer) print() {
+	p.lineStart = true
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '{':
+			p.trimTrailingSpace()
+			if p.lineStart {
+				p.write("{")
+			} else {
+				p.write(" {")
+			}
+			p.newline()
+			p.depth++
+			p.pos++
+		case c == '}':
+			p.depth--
+			if !p.lineStart {
+				p.newline()
+			}
+			p.write("}")
+			p.pos++
+		case c == '(':
+			p.printArgs()
+		case c == '\n':
+			if !p.lineStart {
+				p.newline()
+			}
+			p.pos++
+		case c == ' ' || c == '\t':
+			if !p.lineStart {
+				p.buf.WriteByte(c)
+			}
+			p.pos++
+		case c == '"':
+			p.write(p.readString())
+		default:
+			p.write(string(c))
+			p.pos++
+		}
+	}
+}
+
+// printArgs writes a parenthesised argument list, placing every top level
+// argument on its own line.
+func (p *printer) printArgs() {
+	p.pos++ // (
+	var (
+		args  []string
+		arg   strings.Builder
+		depth int
+	)
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '"' {
+			arg.WriteString(p.readString())
+			continue
+		}
+		p.pos++
+		switch {
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(arg.String()))
+			arg.Reset()
+			continue
+		case c == ')' && depth == 0:
+			if s := strings.TrimSpace(arg.String()); s != "" {
+				args = append(args, s)
+			}
+			p.writeArgs(args)
+			return
+		}
+		arg.WriteByte(c)
+	}
+}
+
+func (p *printer) writeArgs(args []string) {
+	if len(args) == 0 {
+		p.write("()")
+		return
+	}
+	p.write("(")
+	p.newline()
+	p.depth++
+	for i, a := range args {
+		p.write(a)
+		if i < len(args)-1 {
+			p.write(",")
+		}
+		p.newline()
+	}
+	p.depth--
+	p.write(")")
+}
+
+// readString consumes a quoted string literal, including escapes.
+func (p *printer) readString() string {
+	start := p.pos
+	p.pos++ // opening quote
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '"':
+			p.pos++
+			return p.src[start:p.pos]
+		}
+		p.pos++
+	}
+	return p.src[start:]
+}
+
+func (p *printer) write(s string) {
+	if p.lineStart {
+		p.buf.WriteString(strings.Repeat(indentUnit, p.depth))
+		p.lineStart = false
+	}
+	p.buf.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.trimTrailingSpace()
+	p.buf.WriteByte('\n')
+	p.lineStart = true
+}
+
+func (p *printer) trimTrailingSpace() {
+	s := p.buf.String()
+	trimmed := strings.TrimRight(s, " \t")
+	if len(trimmed) != len(s) {
+		p.buf.Reset()
+		p.buf.WriteString(trimmed)
+	}
+}
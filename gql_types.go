@@ -76,6 +76,21 @@ func JSONVar(val interface{}) TypedValue {
 func JSONBVar(val interface{}) TypedValue {
 	return JSONBValue{val}
 }
+func GeometryVar(val interface{}) TypedValue {
+	return GeometryValue{val}
+}
+
+// ArrayVar returns a TypedValue for a list variable, e.g. for use with In. elem
+// converts a single value to its TypedValue and determines the element type,
+// e.g. ArrayVar(IntVar[int], ids) declares the variable as [Int!]!.
+func ArrayVar[T any](elem func(T) TypedValue, vals []T) TypedValue {
+	var zero T
+	list := make([]interface{}, 0, len(vals))
+	for _, v := range vals {
+		list = append(list, elem(v).Value())
+	}
+	return scalarValue{"[" + elem(zero).Type() + "]!", list}
+}
 
 type JSONValue struct {
 	Val interface{}
@@ -98,3 +113,39 @@ func (jv JSONBValue) Type() string {
 func (jv JSONBValue) Value() interface{} {
 	return jv.Val
 }
+
+// GeometryValue is a PostGIS geometry, given as GeoJSON.
+type GeometryValue struct {
+	Val interface{}
+}
+
+func (gv GeometryValue) Type() string {
+	return "geometry"
+}
+func (gv GeometryValue) Value() interface{} {
+	return gv.Val
+}
+
+// Enum is a Go string type for the values of a Hasura enum table. EnumType
+// returns the graphql type name of the enum, e.g. user_state_enum.
+type Enum interface {
+	~string
+	EnumType() string
+}
+
+// EnumValue returns a TypedValue for a variable of val's enum type. The value
+// is sent as a json string, which graphql coerces to the enum value.
+func EnumValue[T Enum](val T) enumTypedValue[T] {
+	return enumTypedValue[T]{val}
+}
+
+type enumTypedValue[T Enum] struct {
+	val T
+}
+
+func (ev enumTypedValue[T]) Type() string {
+	return ev.val.EnumType() + "!"
+}
+func (ev enumTypedValue[T]) Value() interface{} {
+	return string(ev.val)
+}
@@ -0,0 +1,110 @@
+package eywa
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const introspectionQuery = `query eywa_introspection {
+__schema {
+queryType {
+name
+}
+types {
+kind
+name
+fields {
+name
+args {
+name
+}
+type {
+kind
+name
+ofType {
+kind
+name
+ofType {
+kind
+name
+ofType {
+kind
+name
+}
+}
+}
+}
+}
+}
+}
+}`
+
+// IntrospectionResult is the part of the schema served by Hasura that eywa
+// uses: the object types with their fields and the field types.
+type IntrospectionResult struct {
+	// QueryType is the name of the root query type, query_root in Hasura.
+	QueryType string
+	Types     []IntrospectionType
+}
+
+// IntrospectionType is a named type of the schema. Fields is empty for types
+// that aren't objects or interfaces.
+type IntrospectionType struct {
+	Kind   string               `json:"kind"`
+	Name   string               `json:"name"`
+	Fields []IntrospectionField `json:"fields"`
+}
+
+// IntrospectionField is a field of an object type.
+type IntrospectionField struct {
+	Name string               `json:"name"`
+	Args []IntrospectionArg   `json:"args"`
+	Type IntrospectionTypeRef `json:"type"`
+}
+
+// IntrospectionArg is an argument of a field.
+type IntrospectionArg struct {
+	Name string `json:"name"`
+}
+
+// IntrospectionTypeRef is the type of a field. LIST and NON_NULL types wrap
+// OfType, up to three levels deep, e.g. [T!]!.
+type IntrospectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *IntrospectionTypeRef `json:"ofType"`
+}
+
+type introspection struct{}
+
+func (introspection) Query() string {
+	return introspectionQuery
+}
+
+func (introspection) Variables() map[string]interface{} {
+	return nil
+}
+
+// Introspect fetches the types of the schema served by the endpoint. Hasura
+// only allows introspection for roles with introspection enabled.
+func (c *Client) Introspect(ctx context.Context) (*IntrospectionResult, error) {
+	data, err := c.Do(ctx, introspection{})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Schema struct {
+			QueryType struct {
+				Name string `json:"name"`
+			} `json:"queryType"`
+			Types []IntrospectionType `json:"types"`
+		} `json:"__schema"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &IntrospectionResult{
+		QueryType: resp.Schema.QueryType.Name,
+		Types:     resp.Schema.Types,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package eywa
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ApplyFields sets the struct fields of m named by fields to their values. A
+// value can be of the struct field's type, a query variable of such a value,
+// e.g. from a generated <Type>_<Field>Var function, or, for pointer fields,
+// of the type pointed to. A nil value sets the zero value. Unexported fields
+// can't be set; the generated <Type>With functions set them directly.
+func ApplyFields[M Model](m *M, fields ...ModelField[M]) error {
+	v := reflect.ValueOf(m).Elem()
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("eywa: can't apply fields to %T: not a struct", m)
+	}
+	index := make(map[string]int, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if name, ok := jsonFieldName(v.Type().Field(i)); ok {
+			index[name] = i
+		}
+	}
+
+	for _, f := range fields {
+		i, ok := index[f.Name]
+		if !ok {
+			return fmt.Errorf("eywa: %T has no exported field %q", *m, f.Name)
+		}
+		target := v.Field(i)
+		val, ok := fieldValue(f.Value, target.Type())
+		if !ok {
+			return fmt.Errorf("eywa: can't set field %q of %T to a %T", f.Name, *m, f.Value)
+		}
+		target.Set(val)
+	}
+	return nil
+}
+
+// FieldValue returns the value of f as a T, converted the same way as by
+// ApplyFields. It is used by the generated <Type>With functions to set the
+// struct field named by f.
+func FieldValue[T any, M Model](f ModelField[M]) (T, error) {
+	var zero T
+	val, ok := fieldValue(f.Value, reflect.TypeOf(&zero).Elem())
+	if !ok {
+		return zero, fmt.Errorf("eywa: can't set field %q of %T to a %T", f.Name, *new(M), f.Value)
+	}
+	if v, ok := val.Interface().(T); ok {
+		return v, nil
+	}
+	return zero, nil
+}
+
+// fieldValue converts the value of a ModelField to a value of type t. Query
+// variables are unwrapped to the value they send, which for enum variables
+// is a string.
+func fieldValue(value interface{}, t reflect.Type) (reflect.Value, bool) {
+	if var_, ok := value.(queryVar); ok {
+		value = var_.value.Value()
+	}
+	if value == nil {
+		return reflect.Zero(t), true
+	}
+	val := reflect.ValueOf(value)
+	if converted, ok := convertValue(val, t); ok {
+		return converted, true
+	}
+	if t.Kind() == reflect.Ptr {
+		if converted, ok := convertValue(val, t.Elem()); ok {
+			ptr := reflect.New(t.Elem())
+			ptr.Elem().Set(converted)
+			return ptr, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// convertValue converts val to t if it is assignable to t or only differs in
+// the type name, e.g. a string to a named string type.
+func convertValue(val reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	if val.Type().AssignableTo(t) || val.Kind() == t.Kind() && val.Type().ConvertibleTo(t) {
+		return val.Convert(t), true
+	}
+	return reflect.Value{}, false
+}
+
+// jsonFieldName returns the column name of a model struct field from its json
+// tag. Unexported fields and fields tagged json:"-" or eywa:"ignore" are not
+// columns.
+func jsonFieldName(sf reflect.StructField) (string, bool) {
+	if !sf.IsExported() {
+		return "", false
+	}
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" || hasTagOption(sf.Tag.Get("eywa"), "ignore") {
+		return "", false
+	}
+	return name, true
+}
+
+// modelFieldNames returns the column and relationship names of a model
+// struct type.
+func modelFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	names := make(map[string]bool)
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := jsonFieldName(t.Field(i)); ok {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// modelFieldTypes returns the types of the columns of a model struct type by
+// column name. Pointer types are dereferenced.
+func modelFieldTypes(t reflect.Type) map[string]reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	types := make(map[string]reflect.Type)
+	if t.Kind() != reflect.Struct {
+		return types
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if name, ok := jsonFieldName(sf); ok {
+			ft := sf.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			types[name] = ft
+		}
+	}
+	return types
+}
+
+func hasTagOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if strings.TrimSpace(o) == opt {
+			return true
+		}
+	}
+	return false
+}
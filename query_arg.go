@@ -1,6 +1,7 @@
 package eywa
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -12,7 +13,9 @@ type queryArgs[M Model, FN FieldName[M], F Field[M]] struct {
 	distinctOn *distinctOn[M, FN]
 	where      *where
 	orderBy    *orderBy
+	forUpdate  *forUpdate
 	set        *set[M, F]
+	inc        *inc[M, F]
 }
 
 func (qa queryArgs[M, FN, F]) marshalGQL() string {
@@ -22,8 +25,13 @@ func (qa queryArgs[M, FN, F]) marshalGQL() string {
 	args = appendArg(args, qa.distinctOn)
 	args = appendArg(args, qa.where)
 	args = appendArg(args, qa.orderBy)
+	args = appendArg(args, qa.forUpdate)
 	args = appendArg(args, qa.set)
+	args = appendArg(args, qa.inc)
 
+	if len(args) == 0 {
+		return ""
+	}
 	return fmt.Sprintf("(%s)", strings.Join(args, ", "))
 }
 
@@ -61,6 +69,18 @@ func (o offset) marshalGQL() string {
 	return fmt.Sprintf("%s: %d", o.queryArgName(), o)
 }
 
+type forUpdate string
+
+func (fu forUpdate) queryArgName() string {
+	return "for"
+}
+func (fu forUpdate) marshalGQL() string {
+	if fu == "" {
+		return fmt.Sprintf("%s: update", fu.queryArgName())
+	}
+	return fmt.Sprintf("%s: {update: %s}", fu.queryArgName(), string(fu))
+}
+
 type distinctOn[M Model, FN FieldName[M]] struct {
 	field FN
 }
@@ -85,16 +105,38 @@ func (w where) marshalGQL() string {
 
 type set[M Model, F Field[M]] struct {
 	fieldArr[M, F]
+	nulls []string
 }
 
 func (s set[M, F]) queryArgName() string {
 	return "_set"
 }
 func (s set[M, F]) marshalGQL() string {
-	if s.fieldArr == nil || len(s.fieldArr) == 0 {
+	if len(s.fieldArr) == 0 && len(s.nulls) == 0 {
 		return ""
 	}
-	return fmt.Sprintf("%s: {%s}", s.queryArgName(), s.fieldArr.marshalGQL())
+	values := make([]string, 0, 2)
+	if len(s.fieldArr) > 0 {
+		values = append(values, s.fieldArr.marshalGQL())
+	}
+	for _, f := range s.nulls {
+		values = append(values, fmt.Sprintf("%s: null", f))
+	}
+	return fmt.Sprintf("%s: {%s}", s.queryArgName(), strings.Join(values, ", "))
+}
+
+type inc[M Model, F Field[M]] struct {
+	fieldArr[M, F]
+}
+
+func (i inc[M, F]) queryArgName() string {
+	return "_inc"
+}
+func (i inc[M, F]) marshalGQL() string {
+	if len(i.fieldArr) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s: {%s}", i.queryArgName(), i.fieldArr.marshalGQL())
 }
 
 type operator string
@@ -106,12 +148,44 @@ const (
 	gte operator = "_gte"
 	lt  operator = "_lt"
 	lte operator = "_lte"
+	in  operator = "_in"
+
+	like  operator = "_like"
+	ilike operator = "_ilike"
 )
 
+type comparison struct {
+	field string
+	expr  string
+	vars  queryVarArr
+	// err is set if the comparison can't be expressed, e.g. a Cast of an Or.
+	err error
+}
+
+func (c *comparison) marshalGQL() string {
+	return fmt.Sprintf("%s: %s", c.field, c.expr)
+}
+
+func newComparison[M Model, F Field[M]](oprtr operator, field F) comparison {
+	return comparison{
+		field: field.GetName(),
+		expr:  fmt.Sprintf("{%s: %s}", oprtr, field.GetValue()),
+		vars:  fieldVars[M](field),
+	}
+}
+
 func compare[M Model, F Field[M]](oprtr operator, field F) *WhereExpr {
+	cmp := newComparison[M](oprtr, field)
 	return &WhereExpr{
-		cmp: fmt.Sprintf("%s: {%s: %s}", field.GetName(), oprtr, field.GetValue()),
+		cmp: &cmp,
+	}
+}
+
+func fieldVars[M Model, F Field[M]](field F) queryVarArr {
+	if var_, ok := field.GetRawValue().(queryVar); ok {
+		return queryVarArr{var_}
 	}
+	return nil
 }
 
 func Eq[M Model, F Field[M]](field F) *WhereExpr {
@@ -138,6 +212,92 @@ func Lte[M Model, F Field[M]](field F) *WhereExpr {
 	return compare[M](lte, field)
 }
 
+// In matches rows whose column is one of the values of field, which is a
+// slice or a list variable declared with ArrayVar.
+func In[M Model, F Field[M]](field F) *WhereExpr {
+	return compare[M](in, field)
+}
+
+func Like[M Model, F Field[M]](field F) *WhereExpr {
+	return compare[M](like, field)
+}
+
+func Ilike[M Model, F Field[M]](field F) *WhereExpr {
+	return compare[M](ilike, field)
+}
+
+// Cast applies the comparison w to field after casting it to targetType, one
+// of the cast targets Hasura supports for the column (e.g. String), producing
+// {field: {_cast: {targetType: {...}}}}. w must be a comparison such as Eq or
+// Like, or an And of comparisons, which are combined into one comparison
+// expression; their field names are ignored. Any other w makes the query
+// fail to build.
+func Cast[M Model, FN FieldName[M]](field FN, targetType string, w *WhereExpr) *WhereExpr {
+	cmp := comparison{field: string(field)}
+	expr, err := castExpr(w)
+	if err != nil {
+		expr = "{}"
+		cmp.err = fmt.Errorf("eywa: can't cast %s: %w", field, err)
+	}
+	cmp.expr = fmt.Sprintf("{_cast: {%s: %s}}", targetType, expr)
+	cmp.vars = w.queryVars()
+	return &WhereExpr{
+		cmp: &cmp,
+	}
+}
+
+// castExpr returns the comparison expression of w for Cast, e.g.
+// {_gte: 1, _lt: 5} for And(Gte(...), Lt(...)).
+func castExpr(w *WhereExpr) (string, error) {
+	if w == nil {
+		return "{}", nil
+	}
+	if w.cmp != nil && w.and == nil && w.or == nil && w.not == nil {
+		return w.cmp.expr, nil
+	}
+	if w.cmp != nil || w.or != nil || w.not != nil {
+		return "", errors.New("only a comparison or an And of comparisons can be cast")
+	}
+	ops := make([]string, 0, len(w.and))
+	for _, sub := range w.and {
+		expr, err := castExpr(sub)
+		if err != nil {
+			return "", err
+		}
+		if inner := strings.TrimSuffix(strings.TrimPrefix(expr, "{"), "}"); inner != "" {
+			ops = append(ops, inner)
+		}
+	}
+	return fmt.Sprintf("{%s}", strings.Join(ops, ", ")), nil
+}
+
+// CastNumeric applies the comparison w to field after casting it to numeric,
+// e.g. to compare a float column against a rounded value, producing
+// {field: {_cast: {numeric: {...}}}}.
+func CastNumeric[M Model, FN FieldName[M]](field FN, w *WhereExpr) *WhereExpr {
+	return Cast[M](field, "numeric", w)
+}
+
+// RelWhere filters rows of M by the rows of the relationship relField, e.g.
+// RelWhere[User]("profile", Eq[Profile](Profile_VerifiedField(true))) renders
+// {profile: {verified: {_eq: true}}}. For an array relationship it matches if
+// any related row matches cond. A nil cond matches rows with a related row,
+// rendering {profile: {}}.
+func RelWhere[M Model, FN FieldName[M]](relField FN, cond *WhereExpr) *WhereExpr {
+	expr := "{}"
+	if cond != nil {
+		expr = cond.marshalGQL()
+	}
+	return &WhereExpr{
+		cmp: &comparison{
+			field: string(relField),
+			expr:  expr,
+			vars:  cond.queryVars(),
+			err:   cond.buildErr(),
+		},
+	}
+}
+
 func Not(w *WhereExpr) *WhereExpr {
 	return &WhereExpr{
 		not: w,
@@ -160,11 +320,55 @@ type WhereExpr struct {
 	and whereArr
 	or  whereArr
 	not *WhereExpr
-	cmp string
+	cmp *comparison
+}
+
+// Condition is a where expression on M meant to be built once and shared,
+// e.g. as a package level variable applied to many queries. Its expression
+// can't be changed after it is built, so it is safe to use from many
+// goroutines. Build one with Reuse and apply it with WhereCondition on a
+// query builder of M.
+type Condition[M Model] struct {
+	w *WhereExpr
+}
+
+// Reuse returns a Condition with a copy of w. Later changes to the slices or
+// expressions used to build w do not affect the returned Condition.
+func Reuse[M Model](w *WhereExpr) Condition[M] {
+	return Condition[M]{w.clone()}
+}
+
+// Expr returns a copy of the expression of c, e.g. to combine it with other
+// expressions using And.
+func (c Condition[M]) Expr() *WhereExpr {
+	return c.w.clone()
+}
+
+func (w *WhereExpr) clone() *WhereExpr {
+	if w == nil {
+		return nil
+	}
+	return &WhereExpr{
+		and: w.and.clone(),
+		or:  w.or.clone(),
+		not: w.not.clone(),
+		cmp: w.cmp,
+	}
 }
 
 type whereArr []*WhereExpr
 
+func (wa whereArr) clone() whereArr {
+	if wa == nil {
+		return nil
+	}
+	c := make(whereArr, 0, len(wa))
+	for _, w := range wa {
+		c = append(c, w.clone())
+	}
+	return c
+}
+
 func (wa whereArr) marshalGQL() string {
 	stringArr := make([]string, 0, len(wa))
 	for _, whereExpr := range wa {
@@ -176,6 +380,48 @@ func (wa whereArr) marshalGQL() string {
 	return strings.Join(stringArr, ", ")
 }
 
+// queryVars returns the query variables used in w and its sub-expressions.
+func (w *WhereExpr) queryVars() queryVarArr {
+	if w == nil {
+		return nil
+	}
+	var vars queryVarArr
+	for _, sub := range w.and {
+		vars = append(vars, sub.queryVars()...)
+	}
+	for _, sub := range w.or {
+		vars = append(vars, sub.queryVars()...)
+	}
+	vars = append(vars, w.not.queryVars()...)
+	if w.cmp != nil {
+		vars = append(vars, w.cmp.vars...)
+	}
+	return vars
+}
+
+// buildErr returns the error of the first comparison in w that can't be
+// expressed, e.g. a Cast of an Or.
+func (w *WhereExpr) buildErr() error {
+	if w == nil {
+		return nil
+	}
+	if w.cmp != nil && w.cmp.err != nil {
+		return w.cmp.err
+	}
+	for _, sub := range append(append(whereArr{}, w.and...), w.or...) {
+		if err := sub.buildErr(); err != nil {
+			return err
+		}
+	}
+	return w.not.buildErr()
+}
+
+// String returns the GraphQL boolean expression for w, e.g.
+// {name: {_eq: "abcd"}}.
+func (w *WhereExpr) String() string {
+	return w.marshalGQL()
+}
+
 func (w *WhereExpr) marshalGQL() string {
 	if w == nil {
 		return ""
@@ -200,8 +446,8 @@ func (w *WhereExpr) marshalGQL() string {
 		stringArr = append(stringArr, fmt.Sprintf("_not: %s", notExpr))
 	}
 
-	if w.cmp != "" {
-		stringArr = append(stringArr, w.cmp)
+	if w.cmp != nil {
+		stringArr = append(stringArr, w.cmp.marshalGQL())
 	}
 	expr := fmt.Sprintf("{%s}", strings.Join(stringArr, ", "))
 	return expr
@@ -235,6 +481,37 @@ func DescNullsLast[M Model, FN FieldName[M]](field FN) OrderByExpr {
 	return OrderByExpr{"desc_nulls_last", string(field)}
 }
 
+// OrderDirection is the sort direction of an aggregate order by expression.
+type OrderDirection string
+
+const (
+	OrderAsc            OrderDirection = "asc"
+	OrderAscNullsFirst  OrderDirection = "asc_nulls_first"
+	OrderAscNullsLast   OrderDirection = "asc_nulls_last"
+	OrderDesc           OrderDirection = "desc"
+	OrderDescNullsFirst OrderDirection = "desc_nulls_first"
+	OrderDescNullsLast  OrderDirection = "desc_nulls_last"
+)
+
+// OrderByAggregate orders by an aggregate over the rows of the array
+// relationship relField, e.g. OrderByAggregate[User]("posts", Count(), OrderDesc)
+// renders posts_aggregate: {count: desc}.
+func OrderByAggregate[M Model, FN FieldName[M]](relField FN, agg aggregateField, dir OrderDirection) OrderByExpr {
+	order := string(dir)
+	if agg.field != "" {
+		order = fmt.Sprintf("{%s: %s}", agg.field, order)
+	}
+	return OrderByExpr{
+		order: fmt.Sprintf("{%s: %s}", agg.function, order),
+		field: fmt.Sprintf("%s_aggregate", relField),
+	}
+}
+
+// ByCount orders by the number of rows of the array relationship relField.
+func ByCount[M Model, FN FieldName[M]](relField FN, dir OrderDirection) OrderByExpr {
+	return OrderByAggregate[M](relField, Count(), dir)
+}
+
 type orderBy []OrderByExpr
 
 func (oba orderBy) queryArgName() string {
@@ -0,0 +1,59 @@
+package eywa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var operationNamePattern = regexp.MustCompile(`^\s*(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+type queryLogEntry struct {
+	Op         string                 `json:"op"`
+	Query      string                 `json:"query"`
+	Variables  map[string]interface{} `json:"variables"`
+	DurationMS int64                  `json:"duration_ms"`
+	Error      *string                `json:"error"`
+}
+
+// NewQueryLogger returns a ClientMiddleware that writes a JSON line to w for
+// every request, e.g.
+//
+//	{"op":"get_users","query":"...","variables":null,"duration_ms":42,"error":null}
+//
+// op is the operation name of the query. error is only set for requests that
+// failed, e.g. with a transport error or an error status; errors in the
+// graphql response are not logged. Writes to w are serialised.
+func NewQueryLogger(w io.Writer) ClientMiddleware {
+	var mu sync.Mutex
+	return func(next RequestFunc) RequestFunc {
+		return func(ctx context.Context, q Queryable) (*bytes.Buffer, error) {
+			start := time.Now()
+			resp, err := next(ctx, q)
+
+			query := q.Query()
+			entry := queryLogEntry{
+				Query:      query,
+				Variables:  q.Variables(),
+				DurationMS: time.Since(start).Milliseconds(),
+			}
+			if match := operationNamePattern.FindStringSubmatch(query); match != nil {
+				entry.Op = match[1]
+			}
+			if err != nil {
+				msg := err.Error()
+				entry.Error = &msg
+			}
+			line, _ := json.Marshal(entry)
+
+			mu.Lock()
+			w.Write(append(line, '\n'))
+			mu.Unlock()
+			return resp, err
+		}
+	}
+}
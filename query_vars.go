@@ -3,6 +3,7 @@ package eywa
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 )
 
 type queryVar struct {
@@ -32,6 +33,59 @@ func (vs queryVarArr) marshalGQL() string {
 
 }
 
+// values returns the variables map sent with a request, or nil if there are
+// no variables.
+func (vs queryVarArr) values() map[string]interface{} {
+	if len(vs) == 0 {
+		return nil
+	}
+	vars := make(map[string]interface{}, len(vs))
+	for _, var_ := range vs {
+		vars[var_.name] = var_.value.Value()
+	}
+	return vars
+}
+
+// sameValue reports whether v and o have the same type and value, so that
+// both can be sent as one variable.
+func (v queryVar) sameValue(o queryVar) bool {
+	return v.value.Type() == o.value.Type() && reflect.DeepEqual(v.value.Value(), o.value.Value())
+}
+
+// merge drops repeated uses of the same variable, keeping the first. If a
+// name is used with different values, the first value is kept and an error is
+// returned, as only one of them could be sent.
+func (vs queryVarArr) merge() (queryVarArr, error) {
+	var err error
+	seen := make(map[string]queryVar, len(vs))
+	merged := make(queryVarArr, 0, len(vs))
+	for _, var_ := range vs {
+		prev, ok := seen[var_.name]
+		if !ok {
+			seen[var_.name] = var_
+			merged = append(merged, var_)
+			continue
+		}
+		if err == nil && !prev.sameValue(var_) {
+			err = fmt.Errorf("variable $%s is used with different values", var_.name)
+		}
+	}
+	return merged, err
+}
+
+// dedupe drops repeated uses of the same variable, keeping the first.
+func (vs queryVarArr) dedupe() queryVarArr {
+	seen := make(map[string]bool, len(vs))
+	deduped := make(queryVarArr, 0, len(vs))
+	for _, var_ := range vs {
+		if !seen[var_.name] {
+			seen[var_.name] = true
+			deduped = append(deduped, var_)
+		}
+	}
+	return deduped
+}
+
 func QueryVar(name string, value TypedValue) queryVar {
 	return queryVar{name, value}
 }
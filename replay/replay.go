@@ -0,0 +1,148 @@
+// Package replay records the requests sent by an eywa client to a cassette
+// file and replays them later without a network connection, so tests written
+// against a real Hasura instance can run offline.
+//
+//	func TestUsers(t *testing.T) {
+//		var client *eywa.Client
+//		if *record {
+//			client = replay.Record(t, eywa.NewClientWithAdminSecret(endpoint, secret), "testdata/users.json")
+//		} else {
+//			client = replay.Play(t, "testdata/users.json")
+//		}
+//		...
+//	}
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/imperfect-fourth/eywa"
+)
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Query     string          `json:"query"`
+	Variables json.RawMessage `json:"variables,omitempty"`
+	// Response is the response body. It is empty if the request failed.
+	Response json.RawMessage `json:"response,omitempty"`
+	// Error is the message of the error returned for the request, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Record returns a copy of client that records every request and its
+// response. The cassette is written to cassettePath when the test finishes,
+// overwriting any existing file.
+func Record(t testing.TB, client *eywa.Client, cassettePath string) *eywa.Client {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		cassette Cassette
+	)
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		b, err := json.MarshalIndent(cassette, "", "  ")
+		if err != nil {
+			t.Errorf("replay: encoding cassette: %v", err)
+			return
+		}
+		if err := os.WriteFile(cassettePath, append(b, '\n'), 0o644); err != nil {
+			t.Errorf("replay: writing cassette: %v", err)
+		}
+	})
+
+	return client.With(func(next eywa.RequestFunc) eywa.RequestFunc {
+		return func(ctx context.Context, q eywa.Queryable) (*bytes.Buffer, error) {
+			vars, err := encodeVariables(q)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := next(ctx, q)
+
+			interaction := Interaction{Query: q.Query(), Variables: vars}
+			if err != nil {
+				interaction.Error = err.Error()
+			} else {
+				interaction.Response = json.RawMessage(bytes.Clone(resp.Bytes()))
+			}
+			mu.Lock()
+			cassette.Interactions = append(cassette.Interactions, interaction)
+			mu.Unlock()
+			return resp, err
+		}
+	})
+}
+
+// Play returns a client that answers requests from the cassette at
+// cassettePath instead of sending them. Each request is answered by the first
+// interaction not yet replayed with the same query and variables; a request
+// without one fails.
+func Play(t testing.TB, cassettePath string) *eywa.Client {
+	t.Helper()
+	b, err := os.ReadFile(cassettePath)
+	if err != nil {
+		t.Fatalf("replay: reading cassette: %v", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(b, &cassette); err != nil {
+		t.Fatalf("replay: decoding cassette %s: %v", cassettePath, err)
+	}
+	for i, interaction := range cassette.Interactions {
+		if len(interaction.Variables) == 0 {
+			continue
+		}
+		var vars bytes.Buffer
+		if err := json.Compact(&vars, interaction.Variables); err != nil {
+			t.Fatalf("replay: decoding cassette %s: %v", cassettePath, err)
+		}
+		cassette.Interactions[i].Variables = vars.Bytes()
+	}
+
+	var mu sync.Mutex
+	used := make([]bool, len(cassette.Interactions))
+	return eywa.NewClient("http://eywa.replay/v1/graphql", &eywa.ClientOpts{
+		Middlewares: []eywa.ClientMiddleware{func(eywa.RequestFunc) eywa.RequestFunc {
+			return func(ctx context.Context, q eywa.Queryable) (*bytes.Buffer, error) {
+				vars, err := encodeVariables(q)
+				if err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				for i, interaction := range cassette.Interactions {
+					if used[i] || interaction.Query != q.Query() || !bytes.Equal(interaction.Variables, vars) {
+						continue
+					}
+					used[i] = true
+					if interaction.Error != "" {
+						return nil, errors.New(interaction.Error)
+					}
+					return bytes.NewBuffer(bytes.Clone(interaction.Response)), nil
+				}
+				return nil, fmt.Errorf("replay: no recorded response for query %q in %s", q.Query(), cassettePath)
+			}
+		}},
+	})
+}
+
+// encodeVariables encodes the variables of q in their canonical form, so
+// recorded and replayed requests can be compared byte by byte.
+func encodeVariables(q eywa.Queryable) (json.RawMessage, error) {
+	vars := q.Variables()
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(vars)
+}
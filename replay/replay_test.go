@@ -0,0 +1,54 @@
+package replay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/imperfect-fourth/eywa"
+	"github.com/imperfect-fourth/eywa/unsafe"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func (user) ModelName() string {
+	return "users"
+}
+
+func TestRecordAndPlay(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"data": {"users": [{"name": "abc", "age": 3}]}}`))
+	}))
+	defer srv.Close()
+
+	cassette := filepath.Join(t.TempDir(), "users.json")
+	q := unsafe.Get[user]().Where(
+		eywa.Eq[user](eywa.ModelField[user]{Name: "name", Value: eywa.QueryVar("name", eywa.StringVar("abc"))}),
+	).Select("name", "age")
+
+	t.Run("record", func(t *testing.T) {
+		resp, err := q.Exec(context.Background(), Record(t, eywa.NewClient(srv.URL, nil), cassette))
+		assert.NoError(t, err)
+		assert.Equal(t, []user{{"abc", 3}}, resp)
+	})
+	srv.Close()
+
+	t.Run("play", func(t *testing.T) {
+		client := Play(t, cassette)
+		resp, err := q.Exec(context.Background(), client)
+		assert.NoError(t, err)
+		assert.Equal(t, []user{{"abc", 3}}, resp)
+
+		_, err = q.Exec(context.Background(), client)
+		assert.ErrorContains(t, err, "no recorded response")
+	})
+	assert.Equal(t, 1, requests)
+}
@@ -0,0 +1,35 @@
+package eywa
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying id. Requests sent with the
+// returned context have id in their X-Request-ID header, to correlate them
+// with upstream requests.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID set with WithRequestID, or an
+// empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withRequestID adds a generated request ID to ctx if the client has
+// AutoRequestID set and ctx has no ID yet. The ID is added before the
+// middlewares run, so retries of a request share the same ID.
+func (c *Client) withRequestID(ctx context.Context) context.Context {
+	if !c.autoRequestID || RequestIDFromContext(ctx) != "" {
+		return ctx
+	}
+	return WithRequestID(ctx, uuid.NewString())
+}
@@ -0,0 +1,119 @@
+package eywa
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// EywaError is an error from the errors field of a graphql response.
+type EywaError struct {
+	Message    string
+	Extensions map[string]interface{}
+}
+
+func (e EywaError) Error() string {
+	return e.Message
+}
+
+// Code returns the Hasura error code from the extensions of the error, e.g.
+// validation-failed, or an empty string if there is none.
+func (e EywaError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// IsPermissionDenied reports whether the role of the request isn't allowed to
+// run the operation.
+func (e EywaError) IsPermissionDenied() bool {
+	code := e.Code()
+	return code == "access-denied" || code == "permission-denied"
+}
+
+// IsNotFound reports whether Hasura couldn't find what the request refers to.
+func (e EywaError) IsNotFound() bool {
+	return e.Code() == "not-found"
+}
+
+// IsValidationFailed reports whether the query doesn't match the schema, e.g.
+// because it selects an unknown field.
+func (e EywaError) IsValidationFailed() bool {
+	return e.Code() == "validation-failed"
+}
+
+// IsConstraintViolation reports whether a mutation violated a Postgres
+// constraint. Such errors are returned as a ConstraintViolationError by Exec.
+func (e EywaError) IsConstraintViolation() bool {
+	code := e.Code()
+	return code == "constraint-violation" || code == "data-exception"
+}
+
+// Response is the decoded result of a query along with the metadata of the
+// http response it came in.
+type Response[T any] struct {
+	Data       T
+	HTTPStatus int
+	Headers    http.Header
+	// Duration is the time from sending the request until the response body
+	// was read.
+	Duration      time.Duration
+	GraphQLErrors []EywaError
+}
+
+type httpMetaKey struct{}
+
+// httpMeta is filled in by postJSON for requests whose context carries it.
+type httpMeta struct {
+	status   int
+	headers  http.Header
+	duration time.Duration
+}
+
+func withHTTPMeta(ctx context.Context) (context.Context, *httpMeta) {
+	meta := &httpMeta{}
+	return context.WithValue(ctx, httpMetaKey{}, meta), meta
+}
+
+func httpMetaFromContext(ctx context.Context) *httpMeta {
+	meta, _ := ctx.Value(httpMetaKey{}).(*httpMeta)
+	return meta
+}
+
+// execWithResponse sends q and decodes the data field of the response with
+// decode. Graphql errors are returned both in the Response and as the error.
+// The Response is returned whenever the request reached the server, even if
+// it failed.
+func execWithResponse[T any](ctx context.Context, client *Client, q Queryable, decode func(json.RawMessage) (T, error)) (*Response[T], error) {
+	ctx, meta := withHTTPMeta(ctx)
+	respBytes, err := client.do(ctx, q)
+	resp := &Response[T]{
+		HTTPStatus: meta.status,
+		Headers:    meta.headers,
+		Duration:   meta.duration,
+	}
+	if err != nil {
+		if meta.status == 0 {
+			return nil, err
+		}
+		return resp, err
+	}
+
+	respObj := graphqlResponse{}
+	if err := json.NewDecoder(respBytes).Decode(&respObj); err != nil {
+		return resp, err
+	}
+	for _, e := range respObj.Errors {
+		resp.GraphQLErrors = append(resp.GraphQLErrors, EywaError{
+			Message:    e.Message,
+			Extensions: e.Extensions,
+		})
+	}
+	if len(respObj.Data) > 0 {
+		resp.Data, err = decode(respObj.Data)
+		if err != nil {
+			return resp, err
+		}
+	}
+	return resp, graphqlErrorsToError(respObj.Errors)
+}
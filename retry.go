@@ -0,0 +1,77 @@
+package eywa
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy describes how failed requests are retried. Use its Middleware
+// method to install it on a Client via ClientOpts.Middlewares.
+type RetryPolicy struct {
+	Base           time.Duration
+	Multiplier     float64
+	JitterFraction float64
+	// MaxAttempts is the number of retries after the first request. Zero
+	// disables retries.
+	MaxAttempts int
+}
+
+// WithExponentialBackoff returns a RetryPolicy that waits
+// base * multiplier^attempt before each retry, plus a random jitter of up to
+// jitterFraction of that delay so that many clients restarting at once don't
+// retry in lockstep.
+func WithExponentialBackoff(base time.Duration, multiplier float64, jitterFraction float64, maxAttempts int) RetryPolicy {
+	return RetryPolicy{
+		Base:           base,
+		Multiplier:     multiplier,
+		JitterFraction: jitterFraction,
+		MaxAttempts:    maxAttempts,
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.Base) * math.Pow(p.Multiplier, float64(attempt))
+	jitter := rand.Float64() * p.JitterFraction * d
+	return time.Duration(d + jitter)
+}
+
+// Middleware returns a ClientMiddleware that retries transient failures:
+// network errors, responses cut short and 429 and 5xx responses. Other
+// errors, e.g. 4xx responses or a canceled context, are returned as is.
+func (p RetryPolicy) Middleware() ClientMiddleware {
+	return func(next RequestFunc) RequestFunc {
+		return func(ctx context.Context, q Queryable) (*bytes.Buffer, error) {
+			resp, err := next(ctx, q)
+			for attempt := 0; attempt < p.MaxAttempts && isTransient(err); attempt++ {
+				timer := time.NewTimer(p.backoff(attempt))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctx.Err()
+				case <-timer.C:
+				}
+				resp, err = next(ctx, q)
+			}
+			return resp, err
+		}
+	}
+}
+
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var se statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.ErrUnexpectedEOF)
+}
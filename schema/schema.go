@@ -0,0 +1,115 @@
+// Package schema compares eywa models with the schema served by Hasura.
+package schema
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/imperfect-fourth/eywa"
+)
+
+// SchemaDiff lists the differences between a model and the fields of its
+// table in the Hasura schema.
+type SchemaDiff struct {
+	Table string
+	// MissingInDB are model fields that the table doesn't have.
+	MissingInDB []string
+	// MissingInModel are table fields that the model doesn't have. Aggregate
+	// fields of array relationships are left out.
+	MissingInModel []string
+}
+
+// Diff introspects the schema served by client and compares the fields of
+// each model's table with the json tags of the model's struct fields. Only
+// models with differences are included in the result. A table missing from
+// the schema is reported with all model fields in MissingInDB.
+func Diff(ctx context.Context, client *eywa.Client, models ...eywa.Model) ([]SchemaDiff, error) {
+	result, err := client.Introspect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string][]string, len(result.Types))
+	for _, t := range result.Types {
+		fields := make([]string, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			fields = append(fields, f.Name)
+		}
+		tables[t.Name] = fields
+	}
+
+	var diffs []SchemaDiff
+	for _, m := range models {
+		table := eywa.QualifiedModelName(m)
+		diff := diffFields(table, modelFields(m), tables[table])
+		if len(diff.MissingInDB) > 0 || len(diff.MissingInModel) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
+
+func diffFields(table string, modelFields, dbFields []string) SchemaDiff {
+	inModel := make(map[string]bool, len(modelFields))
+	for _, f := range modelFields {
+		inModel[f] = true
+	}
+	inDB := make(map[string]bool, len(dbFields))
+	for _, f := range dbFields {
+		inDB[f] = true
+	}
+
+	diff := SchemaDiff{Table: table}
+	for _, f := range modelFields {
+		if !inDB[f] {
+			diff.MissingInDB = append(diff.MissingInDB, f)
+		}
+	}
+	for _, f := range dbFields {
+		if inModel[f] {
+			continue
+		}
+		if rel, ok := strings.CutSuffix(f, "_aggregate"); ok && inDB[rel] {
+			continue
+		}
+		diff.MissingInModel = append(diff.MissingInModel, f)
+	}
+	sort.Strings(diff.MissingInDB)
+	sort.Strings(diff.MissingInModel)
+	return diff
+}
+
+// modelFields returns the json field names of the model struct, skipping
+// fields tagged json:"-" or eywa:"ignore".
+func modelFields(m eywa.Model) []string {
+	t := reflect.TypeOf(m)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag
+		name, _, _ := strings.Cut(tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if opts, ok := tag.Lookup("eywa"); ok && hasOption(opts, "ignore") {
+			continue
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+func hasOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if strings.TrimSpace(o) == opt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imperfect-fourth/eywa"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Nickname string `json:"nickname,omitempty"`
+	Password string `json:"password" eywa:"ignore"`
+	internal string
+}
+
+func (u user) ModelName() string {
+	return "users"
+}
+
+type post struct {
+	ID int `json:"id"`
+}
+
+func (p post) ModelName() string {
+	return "posts"
+}
+
+type comment struct {
+	ID int `json:"id"`
+}
+
+func (c comment) ModelName() string {
+	return "comments"
+}
+
+func TestDiff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"__schema": {"types": [
+			{"name": "users", "fields": [{"name": "id"}, {"name": "name"}, {"name": "email"}, {"name": "posts"}, {"name": "posts_aggregate"}]},
+			{"name": "posts", "fields": [{"name": "id"}]},
+			{"name": "String", "fields": null}
+		]}}}`))
+	}))
+	defer srv.Close()
+
+	diffs, err := Diff(context.Background(), eywa.NewClient(srv.URL, nil), user{}, &post{}, comment{})
+	assert.NoError(t, err)
+	assert.Equal(t, []SchemaDiff{
+		{
+			Table:          "users",
+			MissingInDB:    []string{"nickname"},
+			MissingInModel: []string{"email", "posts"},
+		},
+		{
+			Table:       "comments",
+			MissingInDB: []string{"id"},
+		},
+	}, diffs)
+}
@@ -0,0 +1,51 @@
+package eywa
+
+// SelectBuilder composes a field selection at runtime, e.g. based on user
+// preferences:
+//
+//	fields := eywa.NewSelectBuilder[User]().
+//		Add(User_ID).
+//		If(withEmail, User_Email).
+//		Build()
+//	unsafe.Get[User]().SelectFields(fields)
+//
+// If no field is added, e.g. because every If condition is false, the query
+// fails with ErrNoFields.
+//
+// Use SelectSet for a fixed list of fields shared between queries.
+type SelectBuilder[M Model] struct {
+	fields []string
+}
+
+// NewSelectBuilder returns an empty SelectBuilder.
+func NewSelectBuilder[M Model]() *SelectBuilder[M] {
+	return &SelectBuilder[M]{}
+}
+
+// If adds fields only if condition is true.
+func (b *SelectBuilder[M]) If(condition bool, fields ...ModelFieldName[M]) *SelectBuilder[M] {
+	if condition {
+		b.Add(fields...)
+	}
+	return b
+}
+
+// Add adds fields to the selection.
+func (b *SelectBuilder[M]) Add(fields ...ModelFieldName[M]) *SelectBuilder[M] {
+	for _, f := range fields {
+		b.fields = append(b.fields, string(f))
+	}
+	return b
+}
+
+// AddRaw adds raw selections, such as relationship selections or computed
+// fields.
+func (b *SelectBuilder[M]) AddRaw(s ...string) *SelectBuilder[M] {
+	b.fields = append(b.fields, s...)
+	return b
+}
+
+// Build returns a copy of the selected fields.
+func (b *SelectBuilder[M]) Build() []string {
+	return append([]string{}, b.fields...)
+}
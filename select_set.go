@@ -0,0 +1,46 @@
+package eywa
+
+// SelectSet is a reusable list of fields of M to select, e.g. shared default
+// fields for a model:
+//
+//	var UserBasicFields = eywa.NewSelectSet[User]().Add(User_ID, User_Name)
+//	eywa.Get[User]().SelectFields(UserBasicFields.Fields())
+//
+// Strings returns the same fields for the builders in package unsafe.
+type SelectSet[M Model] struct {
+	fields []string
+}
+
+// NewSelectSet returns an empty SelectSet.
+func NewSelectSet[M Model]() *SelectSet[M] {
+	return &SelectSet[M]{}
+}
+
+// Add appends fields to the set.
+func (s *SelectSet[M]) Add(fields ...ModelFieldName[M]) *SelectSet[M] {
+	for _, f := range fields {
+		s.fields = append(s.fields, string(f))
+	}
+	return s
+}
+
+// AddRaw appends raw selections to the set, such as relationship selections
+// or computed fields.
+func (s *SelectSet[M]) AddRaw(fields ...string) *SelectSet[M] {
+	s.fields = append(s.fields, fields...)
+	return s
+}
+
+// Fields returns a copy of the fields in the set.
+func (s *SelectSet[M]) Fields() []ModelFieldName[M] {
+	fields := make([]ModelFieldName[M], 0, len(s.fields))
+	for _, f := range s.fields {
+		fields = append(fields, ModelFieldName[M](f))
+	}
+	return fields
+}
+
+// Strings returns a copy of the fields in the set as strings.
+func (s *SelectSet[M]) Strings() []string {
+	return append([]string{}, s.fields...)
+}
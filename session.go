@@ -0,0 +1,58 @@
+package eywa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const adminSecretHeader = "x-hasura-admin-secret"
+
+type sessionKey struct{}
+
+type adminBypassKey struct{}
+
+// WithSession returns a copy of ctx carrying Hasura session variables, e.g.
+// x-hasura-role and x-hasura-user-id. Requests sent with the returned context
+// have the variables set as headers, overriding the client's headers with the
+// same names.
+func WithSession(ctx context.Context, sessionVars map[string]string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sessionVars)
+}
+
+// SessionFromContext returns the session variables set with WithSession, or
+// nil.
+func SessionFromContext(ctx context.Context) map[string]string {
+	vars, _ := ctx.Value(sessionKey{}).(map[string]string)
+	return vars
+}
+
+// WithAdminBypass returns a copy of ctx for requests that need the admin role
+// on a client that otherwise acts as a user role. Requests sent with the
+// returned context are authenticated with the admin secret from the client's
+// headers only: x-hasura-role and all other session variables, whether set in
+// the client's headers or with WithSession, are dropped. Such requests fail if
+// the client has no admin secret header.
+func WithAdminBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminBypassKey{}, true)
+}
+
+func adminBypassFromContext(ctx context.Context) bool {
+	bypass, _ := ctx.Value(adminBypassKey{}).(bool)
+	return bypass
+}
+
+// applyAdminBypass removes the session variable headers from h, keeping the
+// admin secret.
+func applyAdminBypass(h http.Header) error {
+	if h.Get(adminSecretHeader) == "" {
+		return errors.New("eywa: admin bypass requires a client with an admin secret")
+	}
+	for key := range h {
+		if strings.HasPrefix(strings.ToLower(key), "x-hasura-") && !strings.EqualFold(key, adminSecretHeader) {
+			h.Del(key)
+		}
+	}
+	return nil
+}
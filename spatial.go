@@ -0,0 +1,47 @@
+package eywa
+
+import "fmt"
+
+const (
+	stEquals   operator = "_st_equals"
+	stTouches  operator = "_st_touches"
+	stCrosses  operator = "_st_crosses"
+	stOverlaps operator = "_st_overlaps"
+)
+
+// StEquals compares a geometry column with the field value, typically a
+// QueryVar holding a GeometryVar.
+func StEquals[M Model, F Field[M]](field F) *WhereExpr {
+	return compare[M](stEquals, field)
+}
+
+// StTouches matches rows whose geometry column touches the field value, i.e.
+// they share a boundary point but their interiors don't intersect.
+func StTouches[M Model, F Field[M]](field F) *WhereExpr {
+	return compare[M](stTouches, field)
+}
+
+// StCrosses matches rows whose geometry column crosses the field value, i.e.
+// they share some but not all interior points.
+func StCrosses[M Model, F Field[M]](field F) *WhereExpr {
+	return compare[M](stCrosses, field)
+}
+
+// StOverlaps matches rows whose geometry column overlaps the field value, i.e.
+// they share space and are of the same dimension, but neither contains the
+// other.
+func StOverlaps[M Model, F Field[M]](field F) *WhereExpr {
+	return compare[M](stOverlaps, field)
+}
+
+// St3dDWithin matches rows whose geometry column is within distance of the
+// field value in 3D, producing {field: {_st_3d_d_within: {distance: d, from: g}}}.
+func St3dDWithin[M Model, F Field[M]](field F, distance float64) *WhereExpr {
+	return &WhereExpr{
+		cmp: &comparison{
+			field: field.GetName(),
+			expr:  fmt.Sprintf("{_st_3d_d_within: {distance: %v, from: %s}}", distance, field.GetValue()),
+			vars:  fieldVars[M](field),
+		},
+	}
+}
@@ -0,0 +1,178 @@
+// Package testing starts a disposable Hasura instance for integration tests
+// of code using eywa. It is a separate module so that the Docker client
+// dependencies don't become dependencies of eywa itself.
+package testing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imperfect-fourth/eywa"
+	"github.com/testcontainers/testcontainers-go"
+	tcexec "github.com/testcontainers/testcontainers-go/exec"
+	"github.com/testcontainers/testcontainers-go/network"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	postgresImage = "postgres:16-alpine"
+	hasuraImage   = "hasura/graphql-engine:v2.40.0"
+
+	postgresPassword = "postgres"
+	adminSecret      = "eywa-testing-admin-secret"
+)
+
+// StartHasura starts a Postgres and a Hasura container, runs the given SQL
+// migrations in order, tracks every table of the public schema in Hasura
+// and returns a client for it authenticated with the admin secret. The
+// containers are removed when the test finishes. The test is skipped if
+// Docker is not available.
+func StartHasura(t *testing.T, migrations ...string) *eywa.Client {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	ctx := context.Background()
+
+	net, err := network.New(ctx)
+	if err != nil {
+		t.Fatalf("couldn't create docker network: %v", err)
+	}
+	t.Cleanup(func() {
+		net.Remove(ctx)
+	})
+
+	pg := startContainer(t, testcontainers.ContainerRequest{
+		Image:          postgresImage,
+		Env:            map[string]string{"POSTGRES_PASSWORD": postgresPassword},
+		Networks:       []string{net.Name},
+		NetworkAliases: map[string][]string{net.Name: {"postgres"}},
+		WaitingFor: wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).
+			WithStartupTimeout(time.Minute),
+	})
+	for i, m := range migrations {
+		if _, err := psql(ctx, pg, m); err != nil {
+			t.Fatalf("migration %d failed: %v", i, err)
+		}
+	}
+
+	hasura := startContainer(t, testcontainers.ContainerRequest{
+		Image:        hasuraImage,
+		ExposedPorts: []string{"8080/tcp"},
+		Env: map[string]string{
+			"HASURA_GRAPHQL_DATABASE_URL":   fmt.Sprintf("postgres://postgres:%s@postgres:5432/postgres", postgresPassword),
+			"HASURA_GRAPHQL_ADMIN_SECRET":   adminSecret,
+			"HASURA_GRAPHQL_ENABLE_CONSOLE": "false",
+		},
+		Networks: []string{net.Name},
+		WaitingFor: wait.ForHTTP("/healthz").
+			WithPort("8080/tcp").
+			WithStartupTimeout(2 * time.Minute),
+	})
+	endpoint, err := hasura.PortEndpoint(ctx, "8080/tcp", "http")
+	if err != nil {
+		t.Fatalf("couldn't get hasura endpoint: %v", err)
+	}
+
+	tables, err := psql(ctx, pg, "SELECT table_name FROM information_schema.tables WHERE table_schema = 'public'")
+	if err != nil {
+		t.Fatalf("couldn't list tables: %v", err)
+	}
+	if err := trackTables(ctx, endpoint, tables); err != nil {
+		t.Fatalf("couldn't track tables: %v", err)
+	}
+
+	return eywa.NewClientWithAdminSecret(endpoint+"/v1/graphql", adminSecret)
+}
+
+func startContainer(t *testing.T, req testcontainers.ContainerRequest) testcontainers.Container {
+	t.Helper()
+	c, err := testcontainers.GenericContainer(context.Background(), testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if c != nil {
+		t.Cleanup(func() {
+			c.Terminate(context.Background())
+		})
+	}
+	if err != nil {
+		t.Fatalf("couldn't start %s: %v", req.Image, err)
+	}
+	return c
+}
+
+// psql runs sql in the postgres container and returns the rows of the
+// output, one value per line.
+func psql(ctx context.Context, pg testcontainers.Container, sql string) ([]string, error) {
+	code, out, err := pg.Exec(ctx, []string{"psql", "-U", "postgres", "-v", "ON_ERROR_STOP=1", "-At", "-c", sql}, tcexec.Multiplexed())
+	if err != nil {
+		return nil, err
+	}
+	output, err := io.ReadAll(out)
+	if err != nil {
+		return nil, err
+	}
+	if code != 0 {
+		return nil, fmt.Errorf("psql exited with %d: %s", code, output)
+	}
+	var rows []string
+	for _, row := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if row != "" {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func trackTables(ctx context.Context, endpoint string, tables []string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	type tableRef struct {
+		Schema string `json:"schema"`
+		Name   string `json:"name"`
+	}
+	type trackArgs struct {
+		Source string   `json:"source"`
+		Table  tableRef `json:"table"`
+	}
+	type metadataOp struct {
+		Type string      `json:"type"`
+		Args interface{} `json:"args"`
+	}
+	ops := make([]metadataOp, 0, len(tables))
+	for _, table := range tables {
+		ops = append(ops, metadataOp{
+			Type: "pg_track_table",
+			Args: trackArgs{Source: "default", Table: tableRef{Schema: "public", Name: table}},
+		})
+	}
+	body, err := json.Marshal(metadataOp{Type: "bulk", Args: ops})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/v1/metadata", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Hasura-Admin-Secret", adminSecret)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("metadata api returned %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
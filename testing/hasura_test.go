@@ -0,0 +1,29 @@
+package testing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/imperfect-fourth/eywa"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (u user) ModelName() string {
+	return "users"
+}
+
+func TestStartHasura(t *testing.T) {
+	client := StartHasura(t,
+		"CREATE TABLE users (id serial PRIMARY KEY, name text NOT NULL)",
+		"INSERT INTO users (name) VALUES ('eywa')",
+	)
+
+	users, err := eywa.Get[user]().Select("id", "name").Exec(context.Background(), client)
+	assert.NoError(t, err)
+	assert.Equal(t, []user{{ID: 1, Name: "eywa"}}, users)
+}
@@ -0,0 +1,19 @@
+package eywa
+
+import "time"
+
+// TimeRange matches rows where the timestamp column field is between from and
+// to, inclusive. The times are sent in RFC 3339 format with their offset.
+func TimeRange[M Model, FN FieldName[M]](field FN, from, to time.Time) *WhereExpr {
+	return And(
+		Gte[M](ModelField[M]{Name: string(field), Value: from.Format(time.RFC3339Nano)}),
+		Lte[M](ModelField[M]{Name: string(field), Value: to.Format(time.RFC3339Nano)}),
+	)
+}
+
+// Since matches rows where the timestamp column field is within the last d,
+// i.e. TimeRange(field, time.Now().Add(-d), time.Now()).
+func Since[M Model, FN FieldName[M]](field FN, d time.Duration) *WhereExpr {
+	now := time.Now()
+	return TimeRange[M](field, now.Add(-d), now)
+}
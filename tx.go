@@ -0,0 +1,151 @@
+package eywa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// TxMutation is a mutation that can be run in a transaction, e.g. an
+// UpdateQuery.
+type TxMutation interface {
+	marshalGQL() string
+	variables() queryVarArr
+	modelName() string
+}
+
+// TxBuilder collects the mutations of a transaction started with InTx.
+type TxBuilder struct {
+	mutations []TxMutation
+}
+
+// Add appends a mutation to the transaction. Mutations run in the order they
+// are added.
+func (tb *TxBuilder) Add(m TxMutation) {
+	tb.mutations = append(tb.mutations, m)
+}
+
+// InTx combines the mutations added by fns into a single graphql document,
+// which Hasura runs in one Postgres transaction. If a function returns an
+// error, the error is returned by Exec without sending the request.
+func InTx(fns ...func(tx *TxBuilder) error) Tx {
+	tb := &TxBuilder{}
+	for _, fn := range fns {
+		if err := fn(tb); err != nil {
+			return Tx{err: err}
+		}
+	}
+	tx := Tx{mutations: tb.mutations}
+	tx.vars, tx.err = tx.collectVariables()
+	return tx
+}
+
+// Tx is a set of mutations sent in one request. Each mutation is aliased by
+// its index, so the same table can be mutated more than once, and its query
+// variables are renamed with the alias, e.g. $id of the second mutation is
+// sent as $id_m1.
+type Tx struct {
+	mutations []TxMutation
+	vars      queryVarArr
+	err       error
+}
+
+// collectVariables returns the variables of all mutations, renamed with
+// txVarSuffix, and the error of the first mutation that fails to build.
+func (tx Tx) collectVariables() (queryVarArr, error) {
+	var vars queryVarArr
+	for i, m := range tx.mutations {
+		if err := ValidateModelName(m.modelName()); err != nil {
+			return nil, fmt.Errorf("mutation %d: %w", i, err)
+		}
+		if b, ok := m.(builder); ok {
+			if err := b.buildErr(); err != nil {
+				return nil, fmt.Errorf("mutation %d: %w", i, err)
+			}
+		}
+		for _, v := range m.variables() {
+			vars = append(vars, queryVar{v.name + txVarSuffix(i), v.value})
+		}
+	}
+	return vars.merge()
+}
+
+func txAlias(i int) string {
+	return fmt.Sprintf("m%d", i)
+}
+
+// txVarSuffix is appended to the variables of the i-th mutation, so that
+// mutations can use the same variable, e.g. a generated <Type>_IDVar, with
+// different values.
+func txVarSuffix(i int) string {
+	return "_" + txAlias(i)
+}
+
+func (tx Tx) Query() string {
+	buf := bytes.NewBufferString("")
+	for i, m := range tx.mutations {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(txAlias(i))
+		buf.WriteString(": ")
+		names := make(map[string]bool)
+		for _, v := range m.variables() {
+			names[v.name] = true
+		}
+		buf.WriteString(renameVars(m.marshalGQL(), names, txVarSuffix(i)))
+	}
+	return fmt.Sprintf("mutation tx%s {\n%s\n}", tx.vars.marshalGQL(), buf.String())
+}
+
+// String returns the mutation formatted with indentation, for debugging.
+func (tx Tx) String() string {
+	return prettyPrint(tx.Query())
+}
+
+// Validate returns the error from building the transaction, if any, or an
+// error if the combined document is not valid GraphQL.
+func (tx Tx) Validate() error {
+	if tx.err != nil {
+		return tx.err
+	}
+	return validateQuery(tx.Query())
+}
+
+func (tx Tx) Variables() map[string]interface{} {
+	return tx.vars.values()
+}
+
+// Exec sends the transaction. Nothing is sent if building the transaction
+// failed.
+func (tx Tx) Exec(ctx context.Context, client *Client) (TxResult, error) {
+	if tx.err != nil {
+		return TxResult{}, tx.err
+	}
+	data, err := client.Do(ctx, tx)
+	if err != nil {
+		return TxResult{}, err
+	}
+	result := TxResult{}
+	if err := json.Unmarshal(data, &result.data); err != nil {
+		return TxResult{}, err
+	}
+	return result, nil
+}
+
+// TxResult holds the responses of the mutations of a transaction.
+type TxResult struct {
+	data map[string]json.RawMessage
+}
+
+// Get decodes the response of the i-th mutation into dest. For an update
+// mutation the response has the returning and, if requested, affected_rows
+// fields.
+func (r TxResult) Get(i int, dest interface{}) error {
+	raw, ok := r.data[txAlias(i)]
+	if !ok {
+		return fmt.Errorf("no response for mutation %d", i)
+	}
+	return json.Unmarshal(raw, dest)
+}
@@ -2,10 +2,21 @@
 package unsafe
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/imperfect-fourth/eywa"
+	"github.com/imperfect-fourth/eywa/eywatest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,7 +60,7 @@ name
 			},
 		})
 
-		resp, err := q.Exec(c)
+		resp, err := q.Exec(context.Background(), c)
 
 		assert.NoError(t, err)
 		assert.Equal(t, []testTable{{Name: "abcd"}, {Name: "abc"}}, resp)
@@ -86,10 +97,1155 @@ name
 			},
 		})
 
-		resp, err := q.Exec(c)
+		resp, err := q.Exec(context.Background(), c)
 
 		assert.NoError(t, err)
 		n := 3
 		assert.Equal(t, []testTable{{ID: &n, Name: "updatetest"}}, resp)
 	}
 }
+
+func TestQueryString(t *testing.T) {
+	q := Update[testTable]().Where(
+		eywa.Eq[testTable](eywa.RawField{Name: "id", Value: 3}),
+	).Set(
+		eywa.RawField{Name: "name", Value: "a, {b}"},
+		eywa.RawField{Name: "jsonb_col", Value: jsonbcol{StrField: "abcd"}},
+	).Select("name", "id")
+
+	expected := `mutation update_test_table {
+  update_test_table(
+    where: {id: {_eq: 3}},
+    _set: {name: "a, {b}", jsonb_col: "{\"str_field\":\"abcd\",\"int_field\":0,\"bool_field\":false}"}
+  ) {
+    returning {
+      id
+      name
+    }
+  }
+}`
+	assert.Equal(t, expected, q.String())
+}
+
+func TestWhereOrFlat(t *testing.T) {
+	conds := make([]*eywa.WhereExpr, 0, 50)
+	expectedConds := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		conds = append(conds, eywa.Eq[testTable](eywa.RawField{Name: "age", Value: i}))
+		expectedConds = append(expectedConds, fmt.Sprintf("{age: {_eq: %d}}", i))
+	}
+	q := Get[testTable]().Where(eywa.Or(conds...)).Select("name")
+
+	expected := fmt.Sprintf(`query get_test_table {
+test_table(where: {_or: [%s]}) {
+name
+}
+}`, strings.Join(expectedConds, ", "))
+	assert.Equal(t, expected, q.Query())
+}
+
+func TestWhereNested(t *testing.T) {
+	cond := func(name string) *eywa.WhereExpr {
+		return eywa.Eq[testTable](eywa.RawField{Name: "name", Value: name})
+	}
+	w := eywa.And(
+		eywa.Or(cond("a"), cond("b")),
+		eywa.Or(cond("c"), eywa.And(cond("d"), eywa.Or(cond("e"), cond("f")))),
+	)
+
+	expected := `{_and: [{_or: [{name: {_eq: "a"}}, {name: {_eq: "b"}}]}, {_or: [{name: {_eq: "c"}}, {_and: [{name: {_eq: "d"}}, {_or: [{name: {_eq: "e"}}, {name: {_eq: "f"}}]}]}]}]}`
+	assert.Equal(t, expected, w.String())
+}
+
+func TestWhereFromMap(t *testing.T) {
+	fieldMap := map[string]eywa.ModelFieldName[testTable]{
+		"name":  "name",
+		"age":   "age",
+		"id":    "id",
+		"state": "state",
+	}
+	w, err := eywa.WhereFromMap(map[string]string{
+		"name":     "foo",
+		"age_gte":  "10",
+		"id_in":    "1, 2,3",
+		"state":    "active",
+		"name_neq": "bar",
+	}, fieldMap)
+	assert.NoError(t, err)
+	assert.Equal(t, `{_and: [{age: {_gte: 10}}, {id: {_in: [1,2,3]}}, {name: {_eq: "foo"}}, {name: {_neq: "bar"}}, {state: {_eq: active}}]}`, w.String())
+
+	w, err = eywa.WhereFromMap(map[string]string{"age_lt": "3"}, fieldMap)
+	assert.NoError(t, err)
+	assert.Equal(t, `{age: {_lt: 3}}`, w.String())
+
+	_, err = eywa.WhereFromMap(map[string]string{"height_gt": "3"}, fieldMap)
+	assert.Error(t, err)
+
+	_, err = eywa.WhereFromMap(map[string]string{"age": "ten"}, fieldMap)
+	assert.Error(t, err)
+
+	_, err = eywa.WhereFromMap(map[string]string{"state": "x}, _or: [{id: {_gt: 0}}], s: {_eq: y"}, fieldMap)
+	assert.EqualError(t, err, `eywa: filter "state": "x}, _or: [{id: {_gt: 0}}], s: {_eq: y" is not a valid enum value`)
+
+	_, err = eywa.WhereFromMap(map[string]string{"state_in": "active,x}"}, fieldMap)
+	assert.EqualError(t, err, `eywa: filter "state_in": "x}" is not a valid enum value`)
+}
+
+func TestReuseCondition(t *testing.T) {
+	conds := []*eywa.WhereExpr{
+		eywa.Eq[testTable](eywa.RawField{Name: "name", Value: "abcd"}),
+		eywa.Gt[testTable](eywa.RawField{Name: "age", Value: 10}),
+	}
+	cond := eywa.Reuse[testTable](eywa.And(conds...))
+	conds[0] = eywa.Eq[testTable](eywa.RawField{Name: "name", Value: "changed"})
+
+	expected := `query get_test_table {
+test_table(where: {_and: [{name: {_eq: "abcd"}}, {age: {_gt: 10}}]}) {
+name
+}
+}`
+	assert.Equal(t, expected, Get[testTable]().WhereCondition(cond).Select("name").Query())
+	assert.Equal(t, expected, Get[testTable]().WhereCondition(cond).Select("name").Query())
+
+	count := GetCount[testTable]().WhereCondition(cond)
+	assert.Contains(t, count.Query(), `test_table_aggregate(where: {_and: [{name: {_eq: "abcd"}}, {age: {_gt: 10}}]})`)
+
+	q := Get[testTable]().Where(eywa.Not(cond.Expr())).Select("name")
+	assert.Contains(t, q.Query(), `test_table(where: {_not: {_and: [{name: {_eq: "abcd"}}, {age: {_gt: 10}}]}})`)
+}
+
+func TestValidate(t *testing.T) {
+	q := Get[testTable]().Select("name")
+	assert.Equal(t, "query get_test_table {\ntest_table {\nname\n}\n}", q.Query())
+	assert.NoError(t, q.Validate())
+
+	u := Update[testTable]().Set(eywa.RawField{Name: "name", Value: "abcd"}).Select("name")
+	assert.NoError(t, u.Validate())
+}
+
+func TestUpdateAffectedRows(t *testing.T) {
+	q := Update[testTable]().Where(
+		eywa.Eq[testTable](eywa.RawField{Name: "id", Value: 3}),
+	).Set(
+		eywa.RawField{Name: "name", Value: "updatetest"},
+	).AffectedRows().Select("name")
+
+	expected := `mutation update_test_table {
+update_test_table(where: {id: {_eq: 3}}, _set: {name: "updatetest"}) {
+affected_rows
+returning {
+name
+}
+}
+}`
+	assert.Equal(t, expected, q.Query())
+}
+
+func TestWhereCast(t *testing.T) {
+	w := eywa.Cast[testTable]("age", "String", eywa.Like[testTable](eywa.RawField{Name: "age", Value: "1%"}))
+	assert.Equal(t, `{age: {_cast: {String: {_like: "1%"}}}}`, w.String())
+
+	w = eywa.CastNumeric[testTable]("age", eywa.Eq[testTable](eywa.RawField{Name: "age", Value: 3.14}))
+	assert.Equal(t, `{age: {_cast: {numeric: {_eq: 3.14}}}}`, w.String())
+
+	w = eywa.Cast[testTable]("age", "String", eywa.And(
+		eywa.Like[testTable](eywa.RawField{Name: "age", Value: "1%"}),
+		eywa.Neq[testTable](eywa.RawField{Name: "age", Value: eywa.QueryVar("age", eywa.StringVar("10"))}),
+	))
+	q := Get[testTable]().Where(w).Select("name")
+	assert.Equal(t, "query get_test_table($age: String!) {\ntest_table(where: {age: {_cast: {String: {_like: \"1%\", _neq: $age}}}}) {\nname\n}\n}", q.Query())
+	assert.NoError(t, q.Validate())
+
+	w = eywa.Cast[testTable]("age", "String", eywa.Or(
+		eywa.Like[testTable](eywa.RawField{Name: "age", Value: "1%"}),
+		eywa.Like[testTable](eywa.RawField{Name: "age", Value: "2%"}),
+	))
+	q = Get[testTable]().Where(eywa.Not(w)).Select("name")
+	assert.EqualError(t, q.Validate(), "eywa: can't cast age: only a comparison or an And of comparisons can be cast")
+	client, recorder := eywatest.NoopClient()
+	_, err := q.Exec(context.Background(), client)
+	assert.EqualError(t, err, "eywa: can't cast age: only a comparison or an And of comparisons can be cast")
+	assert.Empty(t, recorder.Requests())
+}
+
+func TestCountQuery(t *testing.T) {
+	q := GetCount[testTable]().Where(
+		eywa.Gt[testTable](eywa.RawField{Name: "age", Value: 10}),
+	).Limit(5)
+
+	expected := `query get_test_table_count {
+test_table_aggregate(limit: 5, where: {age: {_gt: 10}}) {
+aggregate {
+count
+}
+}
+}`
+	assert.Equal(t, expected, q.Query())
+	assert.NoError(t, q.Validate())
+}
+
+func TestWhereQueryVar(t *testing.T) {
+	point := map[string]interface{}{"type": "Point", "coordinates": []float64{1, 2, 3}}
+	q := Get[testTable]().Where(
+		eywa.St3dDWithin[testTable](eywa.RawField{Name: "location", Value: eywa.QueryVar("point", eywa.GeometryVar(point))}, 10),
+	).Select("name")
+
+	expected := `query get_test_table($point: geometry) {
+test_table(where: {location: {_st_3d_d_within: {distance: 10, from: $point}}}) {
+name
+}
+}`
+	assert.Equal(t, expected, q.Query())
+	assert.Equal(t, map[string]interface{}{"point": point}, q.Variables())
+}
+
+func TestUpdateSetNull(t *testing.T) {
+	q := Update[testTable]().Where(
+		eywa.Eq[testTable](eywa.RawField{Name: "id", Value: 3}),
+	).SetNull("state").Set(
+		eywa.RawField{Name: "name", Value: "updatetest"},
+	).Select("name")
+
+	expected := `mutation update_test_table {
+update_test_table(where: {id: {_eq: 3}}, _set: {name: "updatetest", state: null}) {
+returning {
+name
+}
+}
+}`
+	assert.Equal(t, expected, q.Query())
+}
+
+func TestSelectExpr(t *testing.T) {
+	q := Get[testTable]().Select("name").SelectExpr("full_name")
+
+	expected := `query get_test_table {
+test_table {
+name
+full_name
+}
+}`
+	assert.Equal(t, expected, q.Query())
+}
+
+func TestWhereBuilder(t *testing.T) {
+	wb := eywa.NewWhereBuilder[testTable, eywa.RawField](4)
+	w := wb.Eq(eywa.RawField{Name: "name", Value: "abcd"}).
+		Gt(eywa.RawField{Name: "age", Value: 10}).
+		Or().
+		Lt(eywa.RawField{Name: "age", Value: 5}).
+		Build()
+	assert.Equal(t, `{_or: [{_and: [{name: {_eq: "abcd"}}, {age: {_gt: 10}}]}, {age: {_lt: 5}}]}`, w.String())
+
+	wb.Reset()
+	assert.Equal(t, `{name: {_neq: "x"}}`, wb.Neq(eywa.RawField{Name: "name", Value: "x"}).Build().String())
+	assert.Equal(t, `{_or: [{_and: [{name: {_eq: "abcd"}}, {age: {_gt: 10}}]}, {age: {_lt: 5}}]}`, w.String())
+}
+
+func TestForUpdate(t *testing.T) {
+	q := Get[testTable]().Limit(1).ForUpdate("skip_locked").Select("id")
+	assert.Equal(t, "query get_test_table {\ntest_table(limit: 1, for: {update: skip_locked}) {\nid\n}\n}", q.Query())
+
+	q = Get[testTable]().ForUpdate("").Select("id")
+	assert.Equal(t, "query get_test_table {\ntest_table(for: update) {\nid\n}\n}", q.Query())
+}
+
+func TestUnsetLimit(t *testing.T) {
+	base := Get[testTable]().Limit(10)
+	assert.Equal(t, "query get_test_table {\ntest_table(limit: 10) {\nname\n}\n}", base.Select("name").Query())
+	assert.Equal(t, "query get_test_table {\ntest_table {\nname\n}\n}", base.UnsetLimit().Select("name").Query())
+	assert.Equal(t, "query get_test_table {\ntest_table {\nname\n}\n}", base.Limit(0).Select("name").Query())
+}
+
+func TestWithCache(t *testing.T) {
+	q := Get[testTable]().WithCache(60).Select("name")
+	assert.Equal(t, "query get_test_table @cached(ttl: 60) {\ntest_table {\nname\n}\n}", q.Query())
+	assert.NoError(t, q.Validate())
+
+	q = Get[testTable]().WithCacheRefresh().Select("name")
+	assert.Equal(t, "query get_test_table @cached(refresh: true) {\ntest_table {\nname\n}\n}", q.Query())
+}
+
+func TestExplain(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/graphql/explain", r.URL.Path)
+		var body struct {
+			Query struct {
+				Query string `json:"query"`
+			} `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(t, "query get_test_table {\ntest_table {\nname\n}\n}", body.Query.Query)
+		w.Write([]byte(`[{"field": "test_table", "sql": "SELECT 1", "plan": ["Aggregate", "  ->  Seq Scan on test_table"]}]`))
+	}))
+	defer srv.Close()
+
+	c := eywa.NewClient(srv.URL+"/v1/graphql", nil)
+	resp, err := c.Explain(context.Background(), Get[testTable]().Select("name"))
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 1", resp.SQL)
+	assert.Equal(t, "Aggregate\n  ->  Seq Scan on test_table", resp.Plan)
+
+	resp, err = Get[testTable]().Select("name").Explain(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, "SELECT 1", resp.SQL)
+}
+
+func TestOrderByAggregate(t *testing.T) {
+	q := Get[testTable]().OrderBy(eywa.ByCount[testTable]("posts", eywa.OrderDesc)).Select("name")
+	assert.Equal(t, "query get_test_table {\ntest_table(order_by: {posts_aggregate: {count: desc}}) {\nname\n}\n}", q.Query())
+
+	q = Get[testTable]().OrderBy(
+		eywa.OrderByAggregate[testTable]("posts", eywa.Sum[testTable]("likes"), eywa.OrderAsc),
+	).Select("name")
+	assert.Equal(t, "query get_test_table {\ntest_table(order_by: {posts_aggregate: {sum: {likes: asc}}}) {\nname\n}\n}", q.Query())
+}
+
+func TestAggregateWhere(t *testing.T) {
+	q := Get[testTable]().Where(eywa.AggregateWhere[testTable]("posts", eywa.Count().Gt(5))).Select("name")
+	assert.Equal(t, "query get_test_table {\ntest_table(where: {posts_aggregate: {count: {predicate: {_gt: 5}}}}) {\nname\n}\n}", q.Query())
+	assert.NoError(t, q.Validate())
+
+	w := eywa.And(
+		eywa.Eq[testTable](eywa.RawField{Name: "name", Value: "abcd"}),
+		eywa.AggregateWhere[testTable]("posts", eywa.Count().Lte(2)),
+	)
+	assert.Equal(t, `{_and: [{name: {_eq: "abcd"}}, {posts_aggregate: {count: {predicate: {_lte: 2}}}}]}`, w.String())
+
+	q = Get[testTable]().Where(eywa.AggregateWhere[testTable]("posts", eywa.Sum[testTable]("likes").Gt(5))).Select("name")
+	assert.EqualError(t, q.Validate(), "eywa: sum aggregate of posts can't be used in a where expression, only count")
+}
+
+func TestWithVariables(t *testing.T) {
+	q := Get[testTable]().Where(
+		eywa.Eq[testTable](eywa.RawField{Name: "name", Value: eywa.QueryVar("name", eywa.StringVar("abc"))}),
+	).WithVariables(map[string]interface{}{
+		"limit": eywa.IntVar(5),
+		"name":  "ignored",
+		"raw":   []int{1, 2},
+	}).Select("name")
+
+	assert.Equal(t, "query get_test_table($name: String!, $limit: Int!) {\ntest_table(where: {name: {_eq: $name}}) {\nname\n}\n}", q.Query())
+	assert.Equal(t, map[string]interface{}{"name": "abc", "limit": 5, "raw": []int{1, 2}}, q.Variables())
+}
+
+func TestAssertModel(t *testing.T) {
+	assert.NotPanics(t, eywa.AssertModel[testTable])
+	assert.PanicsWithValue(t, "eywa: unsafe.jsonbcol does not implement eywa.Model (missing method ModelName() string)", eywa.AssertModel[jsonbcol])
+}
+
+func TestSelectSet(t *testing.T) {
+	s := eywa.NewSelectSet[testTable]().Add("name", "age").AddRaw("full_name")
+	q := Get[testTable]().SelectFields(s.Strings())
+	assert.Equal(t, "query get_test_table {\ntest_table {\nname\nage\nfull_name\n}\n}", q.Query())
+	assert.Equal(t, []eywa.ModelFieldName[testTable]{"name", "age", "full_name"}, s.Fields())
+
+	typed := eywa.Get[testTable]().SelectFields(s.Fields())
+	assert.Equal(t, q.Query(), typed.Query())
+
+	empty := eywa.NewSelectSet[testTable]()
+	assert.ErrorIs(t, eywa.Get[testTable]().SelectFields(empty.Fields()).Validate(), eywa.ErrNoFields)
+}
+
+func TestSelectBuilder(t *testing.T) {
+	fields := eywa.NewSelectBuilder[testTable]().
+		Add("name").
+		If(false, "age").
+		If(true, "id").
+		AddRaw("jsonb_col {str_field}").
+		Build()
+	assert.Equal(t, []string{"name", "id", "jsonb_col {str_field}"}, fields)
+
+	q := Get[testTable]().SelectFields(fields)
+	assert.Equal(t, "query get_test_table {\ntest_table {\nname\nid\njsonb_col {str_field}\n}\n}", q.Query())
+	assert.NoError(t, q.Validate())
+
+	fields = eywa.NewSelectBuilder[testTable]().If(false, "age").Build()
+	assert.Empty(t, fields)
+	client, recorder := eywatest.NoopClient()
+	_, err := Get[testTable]().SelectFields(fields).Exec(context.Background(), client)
+	assert.ErrorIs(t, err, eywa.ErrNoFields)
+	_, err = Update[testTable]().Set(eywa.RawField{Name: "age", Value: 1}).SelectFields(fields).Exec(context.Background(), client)
+	assert.ErrorIs(t, err, eywa.ErrNoFields)
+	assert.Empty(t, recorder.Requests())
+}
+
+func TestExecRaw(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"test_table": [{"name": "abc"}]}}`))
+	}))
+	defer srv.Close()
+	c := eywa.NewClient(srv.URL, nil)
+
+	q := Get[testTable]().Select("name")
+	raw, err := q.ExecRaw(context.Background(), c)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"test_table": [{"name": "abc"}]}`, string(raw))
+
+	resp, err := q.Exec(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, []testTable{{Name: "abc"}}, resp)
+}
+
+func TestExecGraphqlErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors": [{"message": "field not found"}, {"message": "permission denied"}]}`))
+	}))
+	defer srv.Close()
+	c := eywa.NewClient(srv.URL, nil)
+
+	_, err := Update[testTable]().Set(eywa.RawField{Name: "name", Value: "x"}).Select("name").Exec(context.Background(), c)
+	assert.EqualError(t, err, "field not found\npermission denied")
+}
+
+func TestEywaErrorCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors": [{"message": "field 'foo' not found in type: 'test_table'", "extensions": {"code": "validation-failed"}}]}`))
+	}))
+	defer srv.Close()
+
+	_, err := Get[testTable]().Select("foo").Exec(context.Background(), eywa.NewClient(srv.URL, nil))
+	var eErr eywa.EywaError
+	if assert.ErrorAs(t, err, &eErr) {
+		assert.Equal(t, "validation-failed", eErr.Code())
+		assert.True(t, eErr.IsValidationFailed())
+		assert.False(t, eErr.IsPermissionDenied())
+		assert.False(t, eErr.IsNotFound())
+		assert.False(t, eErr.IsConstraintViolation())
+	}
+	assert.True(t, eywa.EywaError{Extensions: map[string]interface{}{"code": "access-denied"}}.IsPermissionDenied())
+}
+
+func TestInTx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"m0": {"returning": [{"name": "a"}]}, "m1": {"affected_rows": 2, "returning": []}}}`))
+	}))
+	defer srv.Close()
+
+	tx := eywa.InTx(
+		func(tx *eywa.TxBuilder) error {
+			tx.Add(Update[testTable]().Where(
+				eywa.Eq[testTable](eywa.RawField{Name: "id", Value: eywa.QueryVar("id", eywa.IntVar(1))}),
+			).Set(eywa.RawField{Name: "name", Value: "a"}).Select("name"))
+			return nil
+		},
+		func(tx *eywa.TxBuilder) error {
+			tx.Add(Update[testTable]().AffectedRows().Where(
+				eywa.Eq[testTable](eywa.RawField{Name: "id", Value: eywa.QueryVar("id", eywa.IntVar(1))}),
+			).Set(eywa.RawField{Name: "age", Value: 3}).Select("name"))
+			return nil
+		},
+	)
+
+	expected := `mutation tx($id_m0: Int!, $id_m1: Int!) {
+m0: update_test_table(where: {id: {_eq: $id_m0}}, _set: {name: "a"}) {
+returning {
+name
+}
+}
+m1: update_test_table(where: {id: {_eq: $id_m1}}, _set: {age: 3}) {
+affected_rows
+returning {
+name
+}
+}
+}`
+	assert.Equal(t, expected, tx.Query())
+	assert.Equal(t, map[string]interface{}{"id_m0": 1, "id_m1": 1}, tx.Variables())
+	assert.NoError(t, tx.Validate())
+
+	result, err := tx.Exec(context.Background(), eywa.NewClient(srv.URL, nil))
+	assert.NoError(t, err)
+	var second struct {
+		AffectedRows int         `json:"affected_rows"`
+		Returning    []testTable `json:"returning"`
+	}
+	assert.NoError(t, result.Get(1, &second))
+	assert.Equal(t, 2, second.AffectedRows)
+	assert.Error(t, result.Get(2, &second))
+}
+
+func TestInTxVariableRenaming(t *testing.T) {
+	tx := eywa.InTx(func(tx *eywa.TxBuilder) error {
+		for _, id := range []int{1, 2} {
+			tx.Add(Update[testTable]().Where(
+				eywa.Eq[testTable](eywa.RawField{Name: "id", Value: eywa.QueryVar("id", eywa.IntVar(id))}),
+			).Set(eywa.RawField{Name: "name", Value: "a"}).Select("name"))
+		}
+		return nil
+	})
+	expected := `mutation tx($id_m0: Int!, $id_m1: Int!) {
+m0: update_test_table(where: {id: {_eq: $id_m0}}, _set: {name: "a"}) {
+returning {
+name
+}
+}
+m1: update_test_table(where: {id: {_eq: $id_m1}}, _set: {name: "a"}) {
+returning {
+name
+}
+}
+}`
+	assert.Equal(t, expected, tx.Query())
+	assert.Equal(t, map[string]interface{}{"id_m0": 1, "id_m1": 2}, tx.Variables())
+	assert.NoError(t, tx.Validate())
+}
+
+func TestVariableCollision(t *testing.T) {
+	age := func(n int) eywa.RawField {
+		return eywa.RawField{Name: "age", Value: eywa.QueryVar("age", eywa.IntVar(n))}
+	}
+	client, recorder := eywatest.NoopClient()
+
+	get := Get[testTable]().Where(eywa.And(
+		eywa.Gte[testTable](age(18)),
+		eywa.Lte[testTable](age(65)),
+	)).Select("name")
+	assert.EqualError(t, get.Validate(), "variable $age is used with different values")
+	_, err := get.Exec(context.Background(), client)
+	assert.EqualError(t, err, "variable $age is used with different values")
+
+	count := eywa.GetCount[testTable]().Where(eywa.Or(
+		eywa.Eq[testTable](age(1)),
+		eywa.Eq[testTable](age(2)),
+	))
+	assert.EqualError(t, count.Validate(), "variable $age is used with different values")
+	_, err = count.Exec(context.Background(), client)
+	assert.EqualError(t, err, "variable $age is used with different values")
+
+	update := Update[testTable]().Where(eywa.Eq[testTable](age(1))).Set(age(2)).Select("name")
+	assert.EqualError(t, update.Validate(), "variable $age is used with different values")
+	_, err = update.Exec(context.Background(), client)
+	assert.EqualError(t, err, "variable $age is used with different values")
+
+	tx := eywa.InTx(func(tx *eywa.TxBuilder) error {
+		tx.Add(update)
+		return nil
+	})
+	assert.EqualError(t, tx.Validate(), "mutation 0: variable $age is used with different values")
+	assert.Empty(t, recorder.Requests())
+
+	same := Get[testTable]().Where(eywa.Or(
+		eywa.Eq[testTable](age(18)),
+		eywa.Gt[testTable](age(18)),
+	)).Select("name")
+	assert.NoError(t, same.Validate())
+	assert.Equal(t, map[string]interface{}{"age": 18}, same.Variables())
+}
+
+type authUser struct {
+	ID int `json:"id"`
+}
+
+func (u authUser) ModelName() string {
+	return "users"
+}
+
+func (u *authUser) ModelSchema() string {
+	return "auth"
+}
+
+func TestValidateModelName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"users", true},
+		{"auth_users", true},
+		{"_private", true},
+		{"table2", true},
+		{"", false},
+		{"2fa", false},
+		{"Users", false},
+		{"user table", false},
+		{"users\"", false},
+		{"users; drop table users", false},
+		{"auth.users", false},
+	}
+	for _, tt := range tests {
+		err := eywa.ValidateModelName(tt.name)
+		if tt.valid {
+			assert.NoError(t, err, tt.name)
+		} else {
+			assert.Error(t, err, tt.name)
+		}
+	}
+}
+
+func TestModelSchema(t *testing.T) {
+	q := Get[authUser]().Select("id")
+	assert.Equal(t, "query get_auth_users {\nauth_users {\nid\n}\n}", q.Query())
+	assert.Equal(t, "test_table", eywa.QualifiedModelName(testTable{}))
+}
+
+func TestDiff(t *testing.T) {
+	id1, id2 := 1, 1
+	old := testTable{Name: "a", Age: 1, ID: &id1}
+	updated := testTable{Name: "b", Age: 1, ID: &id2, JsonBCol: jsonbcol{IntField: 2}}
+
+	fields, err := eywa.Diff(old, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, []eywa.ModelField[testTable]{
+		{Name: "name", Value: "b"},
+		{Name: "jsonb_col", Value: jsonbcol{IntField: 2}},
+	}, fields)
+
+	q := eywa.Update[testTable]().Set(fields...).Select("name")
+	assert.Equal(t, "mutation update_test_table {\nupdate_test_table(where: {_not: {}}, _set: {name: \"b\", jsonb_col: \"{\\\"str_field\\\":\\\"\\\",\\\"int_field\\\":2,\\\"bool_field\\\":false}\"}) {\nreturning {\nname\n}\n}\n}", q.Query())
+}
+
+func TestConstraintViolationError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors": [{"message": "Uniqueness violation. duplicate key value violates unique constraint \"test_table_pkey\"", "extensions": {"code": "constraint-violation", "path": "$.selectionSet.update_test_table.args"}}]}`))
+	}))
+	defer srv.Close()
+
+	_, err := Update[testTable]().Set(eywa.RawField{Name: "id", Value: 1}).Select("name").Exec(context.Background(), eywa.NewClient(srv.URL, nil))
+	cvErr, ok := err.(*eywa.ConstraintViolationError)
+	assert.True(t, ok)
+	assert.Equal(t, "test_table_pkey", cvErr.ConstraintName)
+	assert.Equal(t, "constraint-violation", cvErr.Code)
+	assert.Equal(t, `Uniqueness violation. duplicate key value violates unique constraint "test_table_pkey"`, err.Error())
+}
+
+func TestRequestID(t *testing.T) {
+	var ids []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get("X-Request-ID"))
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	defer srv.Close()
+	q := Get[testTable]().Select("name")
+
+	ctx := eywa.WithRequestID(context.Background(), "req-1")
+	assert.Equal(t, "req-1", eywa.RequestIDFromContext(ctx))
+	_, err := q.Exec(ctx, eywa.NewClient(srv.URL, nil))
+	assert.NoError(t, err)
+	_, err = q.Exec(context.Background(), eywa.NewClient(srv.URL, nil))
+	assert.NoError(t, err)
+	_, err = q.Exec(context.Background(), eywa.NewClient(srv.URL, &eywa.ClientOpts{AutoRequestID: true}))
+	assert.NoError(t, err)
+
+	_, err = eywa.NewClient(srv.URL, &eywa.ClientOpts{AutoRequestID: true}).Explain(context.Background(), q)
+	assert.Error(t, err)
+
+	assert.Len(t, ids, 4)
+	assert.Equal(t, "req-1", ids[0])
+	assert.Equal(t, "", ids[1])
+	assert.Len(t, ids[2], 36)
+	assert.Len(t, ids[3], 36)
+}
+
+func TestStrictSelect(t *testing.T) {
+	client, rec := eywatest.NoopClient()
+
+	_, err := Get[testTable]().StrictSelect().Select("name", "namee").Exec(context.Background(), client)
+	assert.EqualError(t, err, `unknown field "namee" selected on test_table`)
+	assert.Empty(t, rec.Requests())
+
+	q := Get[testTable]().StrictSelect().Select("name", "jsonb_col {int_field}").SelectExpr("full_name")
+	assert.NoError(t, q.Validate())
+	_, err = q.Exec(context.Background(), client)
+	assert.NoError(t, err)
+	assert.Len(t, rec.Requests(), 1)
+}
+
+func TestTimeRange(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 12, 30, 0, 0, time.FixedZone("IST", 5*3600+1800))
+	w := eywa.TimeRange[testTable]("created_at", from, to)
+	assert.Equal(t, `{_and: [{created_at: {_gte: "2024-01-01T00:00:00Z"}}, {created_at: {_lte: "2024-02-01T12:30:00+05:30"}}]}`, w.String())
+
+	w = eywa.Since[testTable]("created_at", time.Hour)
+	assert.True(t, strings.HasPrefix(w.String(), `{_and: [{created_at: {_gte: "`))
+}
+
+func TestRelWhere(t *testing.T) {
+	q := Get[testTable]().Where(
+		eywa.And(
+			eywa.Eq[testTable](eywa.RawField{Name: "name", Value: "abc"}),
+			eywa.RelWhere[testTable]("profile", eywa.Eq[testTable](eywa.RawField{Name: "verified", Value: eywa.QueryVar("verified", eywa.BooleanVar(true))})),
+		),
+	).Select("name")
+	assert.Equal(t, "query get_test_table($verified: Boolean!) {\ntest_table(where: {_and: [{name: {_eq: \"abc\"}}, {profile: {verified: {_eq: $verified}}}]}) {\nname\n}\n}", q.Query())
+	assert.Equal(t, map[string]interface{}{"verified": true}, q.Variables())
+
+	q = Get[testTable]().Where(eywa.RelWhere[testTable]("profile", nil)).Select("name")
+	assert.Equal(t, "query get_test_table {\ntest_table(where: {profile: {}}) {\nname\n}\n}", q.Query())
+	assert.NoError(t, q.Validate())
+}
+
+func TestExecWithResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "hasura")
+		w.Write([]byte(`{"data": {"test_table": [{"name": "abc"}]}, "errors": [{"message": "partial", "extensions": {"code": "unexpected"}}]}`))
+	}))
+	defer srv.Close()
+	c := eywa.NewClient(srv.URL, nil)
+
+	resp, err := Get[testTable]().Select("name").ExecWithResponse(context.Background(), c)
+	assert.EqualError(t, err, "partial")
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, []testTable{{Name: "abc"}}, resp.Data)
+		assert.Equal(t, http.StatusOK, resp.HTTPStatus)
+		assert.Equal(t, "hasura", resp.Headers.Get("X-Served-By"))
+		assert.Equal(t, []eywa.EywaError{{Message: "partial", Extensions: map[string]interface{}{"code": "unexpected"}}}, resp.GraphQLErrors)
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	resp, err = Get[testTable]().Select("name").ExecWithResponse(context.Background(), c)
+	assert.Error(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusServiceUnavailable, resp.HTTPStatus)
+	}
+}
+
+func TestNewClientWithAdminSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("x-hasura-admin-secret"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"data": {"test_table": [{"name": "abcd"}]}}`))
+	}))
+	defer srv.Close()
+
+	c := eywa.NewClientWithAdminSecret(srv.URL, "secret")
+	resp, err := Get[testTable]().Select("name").Exec(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, []testTable{{Name: "abcd"}}, resp)
+}
+
+func TestNewClientWithJWT(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Empty(t, r.Header.Get("x-hasura-admin-secret"))
+		w.Write([]byte(`{"data": {"test_table": [{"name": "abcd"}]}}`))
+	}))
+	defer srv.Close()
+
+	c := eywa.NewClientWithJWT(srv.URL, "token")
+	resp, err := Get[testTable]().Select("name").Exec(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, []testTable{{Name: "abcd"}}, resp)
+}
+
+func TestNewClientWithTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "value", r.Header.Get("x-test"))
+		w.Write([]byte(`{"data": {"test_table": [{"name": "abcd"}]}}`))
+	}))
+	defer srv.Close()
+
+	tlsCfg := srv.Client().Transport.(*http.Transport).TLSClientConfig
+	c := eywa.NewClientWithTLS(srv.URL, tlsCfg, &eywa.ClientOpts{
+		Headers: map[string]string{"x-test": "value"},
+	})
+	resp, err := Get[testTable]().Select("name").Exec(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, []testTable{{Name: "abcd"}}, resp)
+}
+
+func TestWithSession(t *testing.T) {
+	var headers []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header)
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	defer srv.Close()
+	c := eywa.NewClient(srv.URL, &eywa.ClientOpts{
+		Headers: map[string]string{"x-hasura-role": "admin"},
+	})
+	q := Get[testTable]().Select("name")
+
+	ctx := eywa.WithSession(context.Background(), map[string]string{
+		"x-hasura-role":    "user",
+		"x-hasura-user-id": "42",
+	})
+	_, err := q.Exec(ctx, c)
+	assert.NoError(t, err)
+	_, err = q.Exec(context.Background(), c)
+	assert.NoError(t, err)
+
+	assert.Len(t, headers, 2)
+	assert.Equal(t, []string{"user"}, headers[0].Values("x-hasura-role"))
+	assert.Equal(t, "42", headers[0].Get("x-hasura-user-id"))
+	assert.Equal(t, "admin", headers[1].Get("x-hasura-role"))
+	assert.Equal(t, "", headers[1].Get("x-hasura-user-id"))
+}
+
+func TestWithAdminBypass(t *testing.T) {
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	defer srv.Close()
+	c := eywa.NewClient(srv.URL, &eywa.ClientOpts{
+		Headers: map[string]string{
+			"x-hasura-admin-secret": "secret",
+			"x-hasura-role":         "user",
+		},
+	})
+	q := Get[testTable]().Select("name")
+
+	ctx := eywa.WithSession(context.Background(), map[string]string{"x-hasura-user-id": "42"})
+	_, err := q.Exec(eywa.WithAdminBypass(ctx), c)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", headers.Get("x-hasura-admin-secret"))
+	assert.Equal(t, "", headers.Get("x-hasura-role"))
+	assert.Equal(t, "", headers.Get("x-hasura-user-id"))
+
+	_, err = q.Exec(eywa.WithAdminBypass(context.Background()), eywa.NewClient(srv.URL, nil))
+	assert.Error(t, err)
+}
+
+func TestIntrospect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"__schema": {"queryType": {"name": "query_root"}, "types": [
+{"kind": "OBJECT", "name": "test_table", "fields": [{"name": "name", "args": [], "type": {"kind": "NON_NULL", "name": null, "ofType": {"kind": "SCALAR", "name": "String", "ofType": null}}}]}
+]}}}`))
+	}))
+	defer srv.Close()
+
+	result, err := eywa.NewClient(srv.URL, nil).Introspect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "query_root", result.QueryType)
+	if assert.Len(t, result.Types, 1) && assert.Len(t, result.Types[0].Fields, 1) {
+		f := result.Types[0].Fields[0]
+		assert.Equal(t, "name", f.Name)
+		assert.Equal(t, "NON_NULL", f.Type.Kind)
+		assert.Equal(t, "String", f.Type.OfType.Name)
+	}
+}
+
+func TestUpdateMany(t *testing.T) {
+	q := eywa.UpdateMany(
+		Update[testTable]().Where(
+			eywa.Eq[testTable](eywa.RawField{Name: "id", Value: 1}),
+		).Set(eywa.RawField{Name: "name", Value: "first"}),
+		Update[testTable]().Where(
+			eywa.Gt[testTable](eywa.RawField{Name: "age", Value: 10}),
+		).Inc(eywa.RawField{Name: "age", Value: 1}),
+	).Select("id")
+
+	expected := `mutation update_test_table_many {
+update_test_table_many(updates: [{where: {id: {_eq: 1}}, _set: {name: "first"}}, {where: {age: {_gt: 10}}, _inc: {age: 1}}]) {
+affected_rows
+returning {
+id
+}
+}
+}`
+	assert.Equal(t, expected, q.Query())
+	assert.NoError(t, q.Validate())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"update_test_table_many": [{"affected_rows": 1, "returning": [{"id": 1}]}, {"affected_rows": 0, "returning": []}]}}`))
+	}))
+	defer srv.Close()
+
+	rows, affected, err := q.ExecWithAffectedRows(context.Background(), eywa.NewClient(srv.URL, nil))
+	assert.NoError(t, err)
+	one := 1
+	assert.Equal(t, [][]testTable{{{ID: &one}}, {}}, rows)
+	assert.Equal(t, []int{1, 0}, affected)
+}
+
+func TestClientSetHeader(t *testing.T) {
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	defer srv.Close()
+	c := eywa.NewClient(srv.URL, &eywa.ClientOpts{
+		Headers: map[string]string{"Authorization": "Bearer old"},
+	})
+	q := Get[testTable]().Select("name")
+
+	c.SetHeader("Authorization", "Bearer new")
+	c.SetHeader("x-test", "value")
+	_, err := q.Exec(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Bearer new"}, headers.Values("Authorization"))
+	assert.Equal(t, "value", headers.Get("x-test"))
+
+	c.RemoveHeader("x-test")
+	_, err = q.Exec(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, "", headers.Get("x-test"))
+}
+
+func TestQueryLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"data": {"test_table": []}}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	c := eywa.NewClient(srv.URL, &eywa.ClientOpts{
+		Middlewares: []eywa.ClientMiddleware{eywa.NewQueryLogger(&buf)},
+	})
+	q := Get[testTable]().Where(
+		eywa.Eq[testTable](eywa.RawField{Name: "name", Value: eywa.QueryVar("name", eywa.StringVar[string]("abcd"))}),
+	).Select("name")
+	_, err := q.Exec(context.Background(), c)
+	assert.NoError(t, err)
+	c.SetHeader("x-fail", "1")
+	_, err = q.Exec(context.Background(), c)
+	assert.Error(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+		assert.Equal(t, "get_test_table", entry["op"])
+		assert.Equal(t, q.Query(), entry["query"])
+		assert.Equal(t, map[string]interface{}{"name": "abcd"}, entry["variables"])
+		assert.Contains(t, entry, "duration_ms")
+		assert.Nil(t, entry["error"])
+
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+		assert.Equal(t, "error response with http status code: 500", entry["error"])
+	}
+}
+
+func TestArrayVar(t *testing.T) {
+	q := Get[testTable]().Where(
+		eywa.In[testTable](eywa.RawField{Name: "id", Value: eywa.QueryVar("ids", eywa.ArrayVar(eywa.IntVar[int], []int{1, 2, 3}))}),
+	).Select("name")
+
+	expected := `query get_test_table($ids: [Int!]!) {
+test_table(where: {id: {_in: $ids}}) {
+name
+}
+}`
+	assert.Equal(t, expected, q.Query())
+	assert.Equal(t, map[string]interface{}{"ids": []interface{}{1, 2, 3}}, q.Variables())
+	assert.NoError(t, q.Validate())
+
+	w := eywa.In[testTable](eywa.RawField{Name: "id", Value: []int{1, 2}})
+	assert.Equal(t, `{id: {_in: [1,2]}}`, w.String())
+}
+
+func TestExecNullResult(t *testing.T) {
+	for _, body := range []string{`{"data": {"test_table": null}}`, `{"data": null}`} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+		c := eywa.NewClient(srv.URL, nil)
+		q := Get[testTable]().Select("name")
+
+		rows, err := q.Exec(context.Background(), c)
+		assert.NoError(t, err, body)
+		assert.Equal(t, []testTable{}, rows, body)
+
+		resp, err := q.ExecWithResponse(context.Background(), c)
+		assert.NoError(t, err, body)
+		assert.Equal(t, []testTable{}, resp.Data, body)
+		srv.Close()
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	errBackend := errors.New("backend down")
+	type step struct {
+		canceled bool
+		result   error
+		want     error
+	}
+	tests := []struct {
+		name       string
+		threshold  int
+		resetAfter time.Duration
+		steps      []step
+	}{
+		{
+			name:       "opens after threshold failures",
+			threshold:  2,
+			resetAfter: time.Hour,
+			steps: []step{
+				{result: errBackend, want: errBackend},
+				{result: errBackend, want: errBackend},
+				{want: eywa.ErrCircuitOpen},
+			},
+		},
+		{
+			name:       "success resets failures",
+			threshold:  2,
+			resetAfter: time.Hour,
+			steps: []step{
+				{result: errBackend, want: errBackend},
+				{},
+				{result: errBackend, want: errBackend},
+				{},
+			},
+		},
+		{
+			name:       "canceled requests don't count",
+			threshold:  1,
+			resetAfter: time.Hour,
+			steps: []step{
+				{canceled: true, result: context.Canceled, want: context.Canceled},
+				{},
+			},
+		},
+		{
+			name:       "successful probe closes",
+			threshold:  1,
+			resetAfter: 0,
+			steps: []step{
+				{result: errBackend, want: errBackend},
+				{},
+				{result: errBackend, want: errBackend},
+			},
+		},
+		{
+			name:       "failed probe stays open",
+			threshold:  2,
+			resetAfter: 0,
+			steps: []step{
+				{result: errBackend, want: errBackend},
+				{result: errBackend, want: errBackend},
+				{result: errBackend, want: errBackend},
+				{result: errBackend, want: errBackend},
+				{},
+			},
+		},
+		{
+			name:       "canceled probe lets the next request probe",
+			threshold:  1,
+			resetAfter: 0,
+			steps: []step{
+				{result: errBackend, want: errBackend},
+				{canceled: true, result: context.Canceled, want: context.Canceled},
+				{},
+				{result: errBackend, want: errBackend},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result error
+			calls := 0
+			send := eywa.WithCircuitBreaker(tt.threshold, tt.resetAfter)(func(ctx context.Context, q eywa.Queryable) (*bytes.Buffer, error) {
+				calls++
+				return nil, result
+			})
+			for i, s := range tt.steps {
+				ctx, cancel := context.WithCancel(context.Background())
+				if s.canceled {
+					cancel()
+				}
+				result = s.result
+				before := calls
+				_, err := send(ctx, nil)
+				cancel()
+				assert.Equal(t, s.want, err, "step %d", i)
+				if s.want == eywa.ErrCircuitOpen {
+					assert.Equal(t, before, calls, "step %d sent a request", i)
+				}
+			}
+		})
+	}
+
+	assert.Panics(t, func() { eywa.WithCircuitBreaker(0, time.Second) })
+}
+
+func TestCircuitBreakerStaleRequests(t *testing.T) {
+	errBackend := errors.New("backend down")
+	type call struct {
+		result  error
+		started chan struct{}
+		done    chan struct{}
+	}
+	calls := make(chan *call, 10)
+	send := eywa.WithCircuitBreaker(1, 0)(func(ctx context.Context, q eywa.Queryable) (*bytes.Buffer, error) {
+		c := <-calls
+		close(c.started)
+		<-c.done
+		return nil, c.result
+	})
+	start := func(result error) (*call, chan error) {
+		c := &call{result: result, started: make(chan struct{}), done: make(chan struct{})}
+		calls <- c
+		errc := make(chan error, 1)
+		go func() {
+			_, err := send(context.Background(), nil)
+			errc <- err
+		}()
+		<-c.started
+		return c, errc
+	}
+
+	// stale is sent while the circuit is closed and finishes after it opened
+	stale, staleErr := start(nil)
+	failing, failingErr := start(errBackend)
+	close(failing.done)
+	assert.Equal(t, errBackend, <-failingErr)
+
+	probe, probeErr := start(errBackend)
+	close(stale.done)
+	assert.NoError(t, <-staleErr)
+
+	// the stale success neither closed the circuit nor ended the probe
+	_, err := send(context.Background(), nil)
+	assert.Equal(t, eywa.ErrCircuitOpen, err)
+
+	close(probe.done)
+	assert.Equal(t, errBackend, <-probeErr)
+}
+
+// transportError is a net.Error, like the errors of http.Client.Do.
+type transportError struct{}
+
+func (transportError) Error() string   { return "connection reset" }
+func (transportError) Timeout() bool   { return false }
+func (transportError) Temporary() bool { return true }
+
+func TestRetryPolicy(t *testing.T) {
+	policy := eywa.WithExponentialBackoff(time.Millisecond, 2, 0.1, 3)
+	tests := []struct {
+		name      string
+		results   []error
+		wantErr   error
+		wantCalls int
+	}{
+		{"success", []error{nil}, nil, 1},
+		{"retries transport errors", []error{transportError{}, transportError{}, nil}, nil, 3},
+		{"retries unexpected eof", []error{io.ErrUnexpectedEOF, nil}, nil, 2},
+		{"gives up after max attempts", []error{transportError{}, transportError{}, transportError{}, transportError{}}, transportError{}, 4},
+		{"doesn't retry other errors", []error{errors.New("bad request"), nil}, errors.New("bad request"), 1},
+		{"doesn't retry canceled requests", []error{context.Canceled, nil}, context.Canceled, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			send := policy.Middleware()(func(ctx context.Context, q eywa.Queryable) (*bytes.Buffer, error) {
+				calls++
+				return nil, tt.results[calls-1]
+			})
+			_, err := send(context.Background(), nil)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantCalls, calls)
+		})
+	}
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		send := eywa.WithExponentialBackoff(time.Hour, 2, 0, 3).Middleware()(func(ctx context.Context, q eywa.Queryable) (*bytes.Buffer, error) {
+			calls++
+			cancel()
+			return nil, transportError{}
+		})
+		_, err := send(ctx, nil)
+		assert.Equal(t, context.Canceled, err)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -5,7 +5,7 @@ import "github.com/imperfect-fourth/eywa"
 func Get[M eywa.Model, MP eywa.ModelPtr[M]]() eywa.GetQueryBuilder[M, string, eywa.RawField] {
 	return eywa.GetQueryBuilder[M, string, eywa.RawField]{
 		QuerySkeleton: eywa.QuerySkeleton[M, string, eywa.RawField]{
-			ModelName: (*new(M)).ModelName(),
+			ModelName: eywa.QualifiedModelName(MP(new(M))),
 			//			fields:    append(fields, field),
 		},
 	}
@@ -14,8 +14,16 @@ func Get[M eywa.Model, MP eywa.ModelPtr[M]]() eywa.GetQueryBuilder[M, string, ey
 func Update[M eywa.Model, MP eywa.ModelPtr[M]]() eywa.UpdateQueryBuilder[M, string, eywa.RawField] {
 	return eywa.UpdateQueryBuilder[M, string, eywa.RawField]{
 		QuerySkeleton: eywa.QuerySkeleton[M, string, eywa.RawField]{
-			ModelName: (*new(M)).ModelName(),
+			ModelName: eywa.QualifiedModelName(MP(new(M))),
 			//			fields:    append(fields, field),
 		},
 	}
 }
+
+func GetCount[M eywa.Model, MP eywa.ModelPtr[M]]() eywa.CountQuery[M, string, eywa.RawField] {
+	return eywa.CountQuery[M, string, eywa.RawField]{
+		QuerySkeleton: eywa.QuerySkeleton[M, string, eywa.RawField]{
+			ModelName: eywa.QualifiedModelName(MP(new(M))),
+		},
+	}
+}
@@ -1,6 +1,7 @@
 package eywa
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -8,7 +9,7 @@ import (
 func Update[M Model, MP ModelPtr[M]]() UpdateQueryBuilder[M, ModelFieldName[M], ModelField[M]] {
 	return UpdateQueryBuilder[M, ModelFieldName[M], ModelField[M]]{
 		QuerySkeleton: QuerySkeleton[M, ModelFieldName[M], ModelField[M]]{
-			ModelName: (*new(M)).ModelName(),
+			ModelName: QualifiedModelName(MP(new(M))),
 			//			fields:    append(fields, field),
 		},
 	}
@@ -16,10 +17,34 @@ func Update[M Model, MP ModelPtr[M]]() UpdateQueryBuilder[M, ModelFieldName[M],
 
 type UpdateQueryBuilder[M Model, FN FieldName[M], F Field[M]] struct {
 	QuerySkeleton[M, FN, F]
+	affectedRows bool
+}
+
+// AffectedRows adds affected_rows to the mutation response, alongside the
+// returning block. Read it with UpdateQuery.ExecWithAffectedRows.
+func (uq UpdateQueryBuilder[M, FN, F]) AffectedRows() UpdateQueryBuilder[M, FN, F] {
+	uq.affectedRows = true
+	return uq
 }
 
 func (uq UpdateQueryBuilder[M, FN, F]) Set(fields ...F) UpdateQueryBuilder[M, FN, F] {
-	uq.set = &set[M, F]{fieldArr[M, F](fields)}
+	s := set[M, F]{fieldArr: fieldArr[M, F](fields)}
+	if uq.set != nil {
+		s.nulls = uq.set.nulls
+	}
+	uq.set = &s
+	for _, f := range fields {
+		if var_, ok := f.GetRawValue().(queryVar); ok {
+			uq.queryVars = append(uq.queryVars, var_)
+		}
+	}
+	return uq
+}
+
+// Inc increments the given numeric columns by the field values. A negative
+// value decrements the column.
+func (uq UpdateQueryBuilder[M, FN, F]) Inc(fields ...F) UpdateQueryBuilder[M, FN, F] {
+	uq.inc = &inc[M, F]{fieldArr: fieldArr[M, F](fields)}
 	for _, f := range fields {
 		if var_, ok := f.GetRawValue().(queryVar); ok {
 			uq.queryVars = append(uq.queryVars, var_)
@@ -28,11 +53,33 @@ func (uq UpdateQueryBuilder[M, FN, F]) Set(fields ...F) UpdateQueryBuilder[M, FN
 	return uq
 }
 
+// SetNull sets the given nullable columns to null.
+func (uq UpdateQueryBuilder[M, FN, F]) SetNull(fields ...FN) UpdateQueryBuilder[M, FN, F] {
+	s := set[M, F]{}
+	if uq.set != nil {
+		s = *uq.set
+	}
+	nulls := make([]string, 0, len(s.nulls)+len(fields))
+	nulls = append(nulls, s.nulls...)
+	for _, f := range fields {
+		nulls = append(nulls, string(f))
+	}
+	s.nulls = nulls
+	uq.set = &s
+	return uq
+}
+
 func (uq UpdateQueryBuilder[M, FN, F]) Where(w *WhereExpr) UpdateQueryBuilder[M, FN, F] {
 	uq.where = &where{w}
 	return uq
 }
 
+// WhereCondition is like Where with the expression of a Condition on M.
+func (uq UpdateQueryBuilder[M, FN, F]) WhereCondition(c Condition[M]) UpdateQueryBuilder[M, FN, F] {
+	uq.where = &where{c.w}
+	return uq
+}
+
 func (uq *UpdateQueryBuilder[M, FN, F]) marshalGQL() string {
 	if uq.where == nil {
 		uq.where = &where{Not(&WhereExpr{})}
@@ -50,55 +97,130 @@ func (uq UpdateQueryBuilder[M, FN, F]) Select(field FN, fields ...FN) UpdateQuer
 	}
 }
 
+// SelectFields is like Select with the fields in a slice. The mutation fails
+// with ErrNoFields if fields is empty.
+func (uq UpdateQueryBuilder[M, FN, F]) SelectFields(fields []FN) UpdateQuery[M, FN, F] {
+	return UpdateQuery[M, FN, F]{
+		uq:     &uq,
+		fields: append([]FN{}, fields...),
+	}
+}
+
 type UpdateQuery[M Model, FN FieldName[M], F Field[M]] struct {
 	uq     *UpdateQueryBuilder[M, FN, F]
 	fields []FN
 }
 
 func (uq UpdateQuery[M, FN, F]) marshalGQL() string {
+	var affectedRows string
+	if uq.uq.affectedRows {
+		affectedRows = "affected_rows\n"
+	}
 	return fmt.Sprintf(
-		"%s {\nreturning {\n%s\n}\n}",
+		"%s {\n%sreturning {\n%s\n}\n}",
 		uq.uq.marshalGQL(),
+		affectedRows,
 		FieldNameArr[M, FN](uq.fields).marshalGQL(),
 	)
 }
 
+func (uq UpdateQuery[M, FN, F]) variables() queryVarArr {
+	return uq.uq.variables()
+}
+
+func (uq UpdateQuery[M, FN, F]) buildErr() error {
+	if len(uq.fields) == 0 {
+		return ErrNoFields
+	}
+	return uq.uq.buildErr()
+}
+
+func (uq UpdateQuery[M, FN, F]) modelName() string {
+	return uq.uq.ModelName
+}
+
 func (uq UpdateQuery[M, FN, F]) Query() string {
 	return fmt.Sprintf(
 		"mutation update_%s%s {\n%s\n}",
 		uq.uq.ModelName,
-		uq.uq.queryVars.marshalGQL(),
+		uq.uq.variables().marshalGQL(),
 		uq.marshalGQL(),
 	)
 }
 
+// String returns the mutation formatted with indentation, for debugging.
+func (uq UpdateQuery[M, FN, F]) String() string {
+	return prettyPrint(uq.Query())
+}
+
+// Validate parses the generated mutation and returns an error if it is not
+// valid GraphQL, without sending it. Call it at startup to fail early.
+func (uq UpdateQuery[M, FN, F]) Validate() error {
+	if err := uq.buildErr(); err != nil {
+		return err
+	}
+	return validateQuery(uq.Query())
+}
+
 func (uq UpdateQuery[M, FN, F]) Variables() map[string]interface{} {
-	vars := map[string]interface{}{}
-	for _, var_ := range uq.uq.queryVars {
-		vars[var_.name] = var_.value.Value()
+	vars := uq.uq.variables().values()
+	if vars == nil {
+		return map[string]interface{}{}
 	}
 	return vars
 }
 
-func (uq UpdateQuery[M, FN, F]) Exec(client *Client) ([]M, error) {
-	respBytes, err := client.do(uq)
-	if err != nil {
+func (uq UpdateQuery[M, FN, F]) Exec(ctx context.Context, client *Client) ([]M, error) {
+	resp, _, err := uq.ExecWithAffectedRows(ctx, client)
+	return resp, err
+}
+
+// ExecRaw runs the mutation and returns the data field of the response
+// without decoding it.
+func (uq UpdateQuery[M, FN, F]) ExecRaw(ctx context.Context, client *Client) (json.RawMessage, error) {
+	if err := ValidateModelName(uq.uq.ModelName); err != nil {
 		return nil, err
 	}
+	return client.Do(ctx, uq)
+}
 
-	type mutationReturning struct {
-		Returning []M `json:"returning"`
-	}
-	type graphqlResponse struct {
-		Data   map[string]mutationReturning `json:"data"`
-		Errors []graphqlError               `json:"errors"`
+// ExecWithAffectedRows runs the mutation and returns the returning rows along
+// with affected_rows. The count is only populated if AffectedRows was set on
+// the builder.
+func (uq UpdateQuery[M, FN, F]) ExecWithAffectedRows(ctx context.Context, client *Client) ([]M, int, error) {
+	data, err := uq.ExecRaw(ctx, client)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	respObj := graphqlResponse{}
-
-	err = json.NewDecoder(respBytes).Decode(&respObj)
+	resp, err := uq.decode(data)
 	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Returning, resp.AffectedRows, nil
+}
+
+// ExecWithResponse runs the mutation and returns the returning rows along
+// with the http status, headers and duration of the response.
+func (uq UpdateQuery[M, FN, F]) ExecWithResponse(ctx context.Context, client *Client) (*Response[[]M], error) {
+	if err := ValidateModelName(uq.uq.ModelName); err != nil {
 		return nil, err
 	}
-	return respObj.Data[fmt.Sprintf("update_%s", uq.uq.ModelName)].Returning, nil
+	return execWithResponse(ctx, client, uq, func(data json.RawMessage) ([]M, error) {
+		resp, err := uq.decode(data)
+		return resp.Returning, err
+	})
+}
+
+type mutationReturning[M Model] struct {
+	AffectedRows int `json:"affected_rows"`
+	Returning    []M `json:"returning"`
+}
+
+func (uq UpdateQuery[M, FN, F]) decode(data json.RawMessage) (mutationReturning[M], error) {
+	respObj := map[string]mutationReturning[M]{}
+	if err := json.Unmarshal(data, &respObj); err != nil {
+		return mutationReturning[M]{}, err
+	}
+	return respObj[fmt.Sprintf("update_%s", uq.uq.ModelName)], nil
 }
@@ -0,0 +1,236 @@
+package eywa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// UpdateMany combines updates of the same table into one
+// update_<table>_many mutation, which Hasura applies in order in a single
+// transaction. Each update is an Update builder with its own Where and Set,
+// SetNull or Inc; an update without Where matches no rows. Select and
+// AffectedRows are set on the returned builder instead of the updates.
+// Query variables are renamed per update with the index of the update, e.g.
+// $id of the second update is sent as $id_1.
+func UpdateMany[M Model, FN FieldName[M], F Field[M]](updates ...UpdateQueryBuilder[M, FN, F]) UpdateManyQueryBuilder[M, FN, F] {
+	var m M
+	return UpdateManyQueryBuilder[M, FN, F]{
+		modelName: QualifiedModelName(m),
+		updates:   updates,
+	}
+}
+
+type UpdateManyQueryBuilder[M Model, FN FieldName[M], F Field[M]] struct {
+	modelName string
+	updates   []UpdateQueryBuilder[M, FN, F]
+}
+
+// updateVarSuffix is appended to the variables of the i-th update, so that
+// updates can use the same variable, e.g. a generated <Type>_IDVar, with
+// different values.
+func updateVarSuffix(i int) string {
+	return fmt.Sprintf("_%d", i)
+}
+
+// queryVariables returns the variables of all updates, renamed with
+// updateVarSuffix, and the error of the first update that fails to build.
+func (umq UpdateManyQueryBuilder[M, FN, F]) queryVariables() (queryVarArr, error) {
+	var vars queryVarArr
+	for i, uq := range umq.updates {
+		if err := uq.buildErr(); err != nil {
+			return nil, fmt.Errorf("update %d: %w", i, err)
+		}
+		for _, v := range uq.variables() {
+			vars = append(vars, queryVar{v.name + updateVarSuffix(i), v.value})
+		}
+	}
+	return vars.merge()
+}
+
+func (umq UpdateManyQueryBuilder[M, FN, F]) variables() queryVarArr {
+	vars, _ := umq.queryVariables()
+	return vars
+}
+
+func (umq UpdateManyQueryBuilder[M, FN, F]) buildErr() error {
+	_, err := umq.queryVariables()
+	return err
+}
+
+func (umq UpdateManyQueryBuilder[M, FN, F]) marshalGQL() string {
+	updates := make([]string, 0, len(umq.updates))
+	for i, uq := range umq.updates {
+		names := make(map[string]bool)
+		for _, v := range uq.variables() {
+			names[v.name] = true
+		}
+		updates = append(updates, renameVars(uq.updateManyArg(), names, updateVarSuffix(i)))
+	}
+	return fmt.Sprintf("update_%s_many(updates: [%s])", umq.modelName, strings.Join(updates, ", "))
+}
+
+// updateManyArg returns the update as an element of the updates argument of
+// update_<table>_many, e.g. {where: {id: {_eq: 1}}, _set: {name: "a"}}.
+func (uq UpdateQueryBuilder[M, FN, F]) updateManyArg() string {
+	w := uq.where
+	if w == nil {
+		w = &where{Not(&WhereExpr{})}
+	}
+	var args []string
+	args = appendArg(args, w)
+	args = appendArg(args, uq.set)
+	args = appendArg(args, uq.inc)
+	return fmt.Sprintf("{%s}", strings.Join(args, ", "))
+}
+
+// renameVars appends suffix to the references to the variables in names in
+// the rendered arguments s. String literals are copied as is.
+func renameVars(s string, names map[string]bool, suffix string) string {
+	var buf strings.Builder
+	inString := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		buf.WriteByte(c)
+		switch {
+		case inString && c == '\\' && i+1 < len(s):
+			i++
+			buf.WriteByte(s[i])
+		case inString && c == '"':
+			inString = false
+		case inString:
+		case c == '"':
+			inString = true
+		case c == '$':
+			end := i + 1
+			for end < len(s) && isNameChar(s[end]) {
+				end++
+			}
+			name := s[i+1 : end]
+			buf.WriteString(name)
+			if names[name] {
+				buf.WriteString(suffix)
+			}
+			i = end - 1
+		}
+	}
+	return buf.String()
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (umq UpdateManyQueryBuilder[M, FN, F]) Select(field FN, fields ...FN) UpdateManyQuery[M, FN, F] {
+	return UpdateManyQuery[M, FN, F]{
+		umq:    &umq,
+		fields: append(fields, field),
+	}
+}
+
+// SelectFields is like Select with the fields in a slice. The mutation fails
+// with ErrNoFields if fields is empty.
+func (umq UpdateManyQueryBuilder[M, FN, F]) SelectFields(fields []FN) UpdateManyQuery[M, FN, F] {
+	return UpdateManyQuery[M, FN, F]{
+		umq:    &umq,
+		fields: append([]FN{}, fields...),
+	}
+}
+
+type UpdateManyQuery[M Model, FN FieldName[M], F Field[M]] struct {
+	umq    *UpdateManyQueryBuilder[M, FN, F]
+	fields []FN
+}
+
+func (uq UpdateManyQuery[M, FN, F]) marshalGQL() string {
+	return fmt.Sprintf(
+		"%s {\naffected_rows\nreturning {\n%s\n}\n}",
+		uq.umq.marshalGQL(),
+		FieldNameArr[M, FN](uq.fields).marshalGQL(),
+	)
+}
+
+func (uq UpdateManyQuery[M, FN, F]) variables() queryVarArr {
+	return uq.umq.variables()
+}
+
+func (uq UpdateManyQuery[M, FN, F]) buildErr() error {
+	if len(uq.fields) == 0 {
+		return ErrNoFields
+	}
+	return uq.umq.buildErr()
+}
+
+func (uq UpdateManyQuery[M, FN, F]) modelName() string {
+	return uq.umq.modelName
+}
+
+func (uq UpdateManyQuery[M, FN, F]) Query() string {
+	return fmt.Sprintf(
+		"mutation update_%s_many%s {\n%s\n}",
+		uq.umq.modelName,
+		uq.umq.variables().marshalGQL(),
+		uq.marshalGQL(),
+	)
+}
+
+// String returns the mutation formatted with indentation, for debugging.
+func (uq UpdateManyQuery[M, FN, F]) String() string {
+	return prettyPrint(uq.Query())
+}
+
+// Validate parses the generated mutation and returns an error if it is not
+// valid GraphQL, without sending it. Call it at startup to fail early.
+func (uq UpdateManyQuery[M, FN, F]) Validate() error {
+	if err := uq.buildErr(); err != nil {
+		return err
+	}
+	return validateQuery(uq.Query())
+}
+
+func (uq UpdateManyQuery[M, FN, F]) Variables() map[string]interface{} {
+	vars := uq.umq.variables().values()
+	if vars == nil {
+		return map[string]interface{}{}
+	}
+	return vars
+}
+
+// Exec runs the mutation and returns the returning rows of each update, in
+// the order of the updates.
+func (uq UpdateManyQuery[M, FN, F]) Exec(ctx context.Context, client *Client) ([][]M, error) {
+	resp, _, err := uq.ExecWithAffectedRows(ctx, client)
+	return resp, err
+}
+
+// ExecRaw runs the mutation and returns the data field of the response
+// without decoding it.
+func (uq UpdateManyQuery[M, FN, F]) ExecRaw(ctx context.Context, client *Client) (json.RawMessage, error) {
+	if err := ValidateModelName(uq.umq.modelName); err != nil {
+		return nil, err
+	}
+	return client.Do(ctx, uq)
+}
+
+// ExecWithAffectedRows runs the mutation and returns the returning rows and
+// affected_rows of each update, in the order of the updates.
+func (uq UpdateManyQuery[M, FN, F]) ExecWithAffectedRows(ctx context.Context, client *Client) ([][]M, []int, error) {
+	data, err := uq.ExecRaw(ctx, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	respObj := map[string][]mutationReturning[M]{}
+	if err := json.Unmarshal(data, &respObj); err != nil {
+		return nil, nil, err
+	}
+	results := respObj[fmt.Sprintf("update_%s_many", uq.umq.modelName)]
+	rows := make([][]M, 0, len(results))
+	affected := make([]int, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, r.Returning)
+		affected = append(affected, r.AffectedRows)
+	}
+	return rows, affected, nil
+}
@@ -0,0 +1,13 @@
+package eywa
+
+import (
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// validateQuery checks that query is syntactically valid GraphQL. It does not
+// validate the query against a schema.
+func validateQuery(query string) error {
+	_, err := parser.ParseQuery(&ast.Source{Input: query})
+	return err
+}
@@ -0,0 +1,119 @@
+package eywa
+
+// WhereBuilder accumulates conditions for a where expression without
+// allocating an expression per condition. Conditions added after each other
+// are ANDed; Or starts a new group that is ORed with the previous ones.
+// After Build, the builder can be cleared with Reset and reused, e.g. from a
+// sync.Pool. A WhereBuilder is not safe for concurrent use.
+type WhereBuilder[M Model, F Field[M]] struct {
+	entries     []whereEntry
+	groupStarts []int
+}
+
+type whereEntry struct {
+	cmp  comparison
+	expr *WhereExpr
+}
+
+// NewWhereBuilder returns a WhereBuilder with room for capacity conditions.
+func NewWhereBuilder[M Model, F Field[M]](capacity int) *WhereBuilder[M, F] {
+	return &WhereBuilder[M, F]{
+		entries: make([]whereEntry, 0, capacity),
+	}
+}
+
+func (wb *WhereBuilder[M, F]) add(oprtr operator, field F) *WhereBuilder[M, F] {
+	wb.entries = append(wb.entries, whereEntry{cmp: newComparison[M](oprtr, field)})
+	return wb
+}
+
+func (wb *WhereBuilder[M, F]) Eq(field F) *WhereBuilder[M, F] {
+	return wb.add(eq, field)
+}
+
+func (wb *WhereBuilder[M, F]) Neq(field F) *WhereBuilder[M, F] {
+	return wb.add(neq, field)
+}
+
+func (wb *WhereBuilder[M, F]) Gt(field F) *WhereBuilder[M, F] {
+	return wb.add(gt, field)
+}
+
+func (wb *WhereBuilder[M, F]) Gte(field F) *WhereBuilder[M, F] {
+	return wb.add(gte, field)
+}
+
+func (wb *WhereBuilder[M, F]) Lt(field F) *WhereBuilder[M, F] {
+	return wb.add(lt, field)
+}
+
+func (wb *WhereBuilder[M, F]) Lte(field F) *WhereBuilder[M, F] {
+	return wb.add(lte, field)
+}
+
+// And adds already built expressions to the current group.
+func (wb *WhereBuilder[M, F]) And(w ...*WhereExpr) *WhereBuilder[M, F] {
+	for _, expr := range w {
+		wb.entries = append(wb.entries, whereEntry{expr: expr})
+	}
+	return wb
+}
+
+// Or ends the current group of conditions; following conditions form a new
+// group that is ORed with the previous ones.
+func (wb *WhereBuilder[M, F]) Or() *WhereBuilder[M, F] {
+	start := len(wb.entries)
+	last := 0
+	if len(wb.groupStarts) > 0 {
+		last = wb.groupStarts[len(wb.groupStarts)-1]
+	}
+	if start > last {
+		wb.groupStarts = append(wb.groupStarts, start)
+	}
+	return wb
+}
+
+// Reset clears the builder, keeping its allocated capacity.
+func (wb *WhereBuilder[M, F]) Reset() {
+	wb.entries = wb.entries[:0]
+	wb.groupStarts = wb.groupStarts[:0]
+}
+
+// Build returns the accumulated where expression. The result doesn't share
+// memory with the builder, so it stays valid after Reset.
+func (wb *WhereBuilder[M, F]) Build() *WhereExpr {
+	exprs := make([]WhereExpr, len(wb.entries))
+	cmps := make([]comparison, len(wb.entries))
+	for i, e := range wb.entries {
+		if e.expr != nil {
+			exprs[i] = *e.expr
+			continue
+		}
+		cmps[i] = e.cmp
+		exprs[i].cmp = &cmps[i]
+	}
+
+	group := func(start, end int) *WhereExpr {
+		if end-start == 1 {
+			return &exprs[start]
+		}
+		arr := make(whereArr, 0, end-start)
+		for i := start; i < end; i++ {
+			arr = append(arr, &exprs[i])
+		}
+		return &WhereExpr{and: arr}
+	}
+
+	bounds := append([]int{0}, wb.groupStarts...)
+	bounds = append(bounds, len(exprs))
+	if len(bounds) == 2 {
+		return group(0, len(exprs))
+	}
+	or := make(whereArr, 0, len(bounds)-1)
+	for i := 0; i < len(bounds)-1; i++ {
+		if bounds[i] < bounds[i+1] {
+			or = append(or, group(bounds[i], bounds[i+1]))
+		}
+	}
+	return &WhereExpr{or: or}
+}
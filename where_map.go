@@ -0,0 +1,141 @@
+package eywa
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// whereMapOperators are the key suffixes WhereFromMap understands. Longer
+// suffixes come first so that e.g. age_gte isn't read as age_gt.
+var whereMapOperators = []operator{gte, lte, neq, ilike, gt, lt, eq, like, in}
+
+// enumValuePattern matches a graphql enum value. Values of types that render
+// themselves unquoted, like HasuraEnum, must match it so that a filter
+// parameter can't inject graphql into the query.
+var enumValuePattern = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+var gqlMarshalerType = reflect.TypeOf((*gqlMarshaler)(nil)).Elem()
+
+// WhereFromMap builds a where expression from filter parameters such as URL
+// query values, e.g. {"name": "foo", "age_gt": "10"} becomes
+// {_and: [{age: {_gt: 10}}, {name: {_eq: "foo"}}]}. A key is a name from
+// fieldMap optionally followed by one of the suffixes _eq, _neq, _gt, _gte,
+// _lt, _lte, _like, _ilike or _in; a key without a suffix is compared with
+// _eq. Values are parsed as the type of the model field, which must be a
+// string, integer, float or bool, and values for _in are comma separated.
+// Values of enum fields like HasuraEnum must be valid graphql enum values.
+// Conditions are ANDed in key order. Unknown keys and unparsable values are
+// an error.
+func WhereFromMap[M Model](params map[string]string, fieldMap map[string]ModelFieldName[M]) (*WhereExpr, error) {
+	types := modelFieldTypes(reflect.TypeOf((*M)(nil)).Elem())
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conds := make([]*WhereExpr, 0, len(keys))
+	for _, key := range keys {
+		field, oprtr, ok := parseWhereMapKey(key, fieldMap)
+		if !ok {
+			return nil, fmt.Errorf("eywa: unknown filter %q", key)
+		}
+		t, ok := types[string(field)]
+		if !ok {
+			return nil, fmt.Errorf("eywa: filter %q: %s is not a field of %T", key, field, *new(M))
+		}
+
+		var (
+			val interface{}
+			err error
+		)
+		if oprtr == in {
+			val, err = parseWhereMapList(params[key], t)
+		} else {
+			val, err = parseWhereMapValue(params[key], t)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("eywa: filter %q: %w", key, err)
+		}
+		conds = append(conds, compare[M](oprtr, ModelField[M]{Name: string(field), Value: val}))
+	}
+
+	if len(conds) == 1 {
+		return conds[0], nil
+	}
+	return And(conds...), nil
+}
+
+func parseWhereMapKey[M Model](key string, fieldMap map[string]ModelFieldName[M]) (ModelFieldName[M], operator, bool) {
+	if field, ok := fieldMap[key]; ok {
+		return field, eq, true
+	}
+	for _, oprtr := range whereMapOperators {
+		name, found := strings.CutSuffix(key, string(oprtr))
+		if !found {
+			continue
+		}
+		if field, ok := fieldMap[name]; ok {
+			return field, oprtr, true
+		}
+	}
+	return "", "", false
+}
+
+// parseWhereMapValue parses s as a value of type t.
+func parseWhereMapValue(s string, t reflect.Type) (interface{}, error) {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		if t.Implements(gqlMarshalerType) && !enumValuePattern.MatchString(s) {
+			return nil, fmt.Errorf("%q is not a valid enum value", s)
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+		v.SetFloat(f)
+	default:
+		return nil, fmt.Errorf("can't filter on a field of type %s", t)
+	}
+	return v.Interface(), nil
+}
+
+// parseWhereMapList parses the comma separated values in s as a slice of t.
+func parseWhereMapList(s string, t reflect.Type) (interface{}, error) {
+	parts := strings.Split(s, ",")
+	list := reflect.MakeSlice(reflect.SliceOf(t), 0, len(parts))
+	for _, p := range parts {
+		val, err := parseWhereMapValue(strings.TrimSpace(p), t)
+		if err != nil {
+			return nil, err
+		}
+		list = reflect.Append(list, reflect.ValueOf(val))
+	}
+	return list.Interface(), nil
+}